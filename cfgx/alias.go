@@ -0,0 +1,93 @@
+package cfgx
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	tagAlias      = "alias"      // Other names of the field, e.g. "Hostname,env:DB_HOST"
+	tagDeprecated = "deprecated" // Old names of the field, reported as a Deprecation
+)
+
+// Kinds of names for the alias and deprecated tags.
+const (
+	nameEnv    = "env"
+	nameFlag   = "flag"
+	nameSecret = "secret"
+)
+
+// Deprecation is reported when a source sets a field with a name in its
+// "deprecated" tag. Pass [Options.OnDeprecated] to handle it, otherwise
+// it is logged with slog as a warning.
+type Deprecation struct {
+	Field       string `json:"field"`       // Path of the field, e.g. DB.Host
+	Kind        string `json:"kind"`        // Kind of name: env, flag or secret
+	Name        string `json:"name"`        // Deprecated name, e.g. APP_DB_HOSTNAME
+	Replacement string `json:"replacement"` // Name to use instead, e.g. APP_DB_HOST
+}
+
+// altName is an alias or deprecated name of a field.
+type altName struct {
+	name       string
+	deprecated bool
+}
+
+// altNames returns the names in the alias and deprecated tags for the
+// kind of name. Names prefixed with a kind, e.g. "env:DB_HOST", are
+// used as is for that kind only. Other names are old field paths and
+// converted with toName, e.g. "DB.Hostname" to APP_DB_HOSTNAME.
+func (f ConfigField) altNames(kind string, toName func(path string) string) []altName {
+	var names []altName
+
+	for _, tag := range []string{tagAlias, tagDeprecated} {
+		for _, item := range splitList(f.Tag.Get(tag), defaultSeparator) {
+			name := toName(item)
+			if k, n, ok := strings.Cut(item, ":"); ok {
+				if k != kind {
+					continue
+				}
+				name = n
+			}
+			names = append(names, altName{name: name, deprecated: tag == tagDeprecated})
+		}
+	}
+
+	return names
+}
+
+// lookupNames looks up the field by its name and its other names.
+// It reports a deprecated name that is set, and fails if two names
+// are set to different values.
+func (f ConfigField) lookupNames(kind, name string, toName func(path string) string, lookup func(name string) (string, bool)) (string, bool, error) {
+	value, found := lookup(name)
+	setBy := name
+
+	for _, alt := range f.altNames(kind, toName) {
+		altValue, ok := lookup(alt.name)
+		if !ok {
+			continue
+		}
+
+		if alt.deprecated {
+			f.deprecated(Deprecation{Field: f.Path, Kind: kind, Name: alt.name, Replacement: name})
+		}
+
+		if found && altValue != value {
+			return "", false, &fieldError{f.Path, fmt.Errorf("%s and %s are both set to different values", alt.name, setBy)}
+		}
+		value, found, setBy = altValue, true, alt.name
+	}
+
+	return value, found, nil
+}
+
+// deprecated reports the use of a deprecated name.
+func (f ConfigField) deprecated(d Deprecation) {
+	if f.state != nil && f.state.onDeprecated != nil {
+		f.state.onDeprecated(d)
+		return
+	}
+	slog.Warn("Deprecated config name.", "field", d.Field, "kind", d.Kind, "name", d.Name, "replacement", d.Replacement)
+}
@@ -0,0 +1,159 @@
+package cfgx_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type aliasConfig struct {
+	DB struct {
+		Host     string `deprecated:"DB.Hostname,env:DATABASE_HOST" optional:"true"`
+		Port     int    `alias:"DB.PortNumber" default:"5432"`
+		Password string `secret:"true" deprecated:"secret:db_pass" optional:"true"`
+	}
+}
+
+func TestAlias_Env(t *testing.T) {
+	t.Run("Deprecated", func(t *testing.T) {
+		os.Setenv("ALIAS_DB_HOSTNAME", "old.internal")
+		os.Setenv("DATABASE_HOST", "old.internal")
+		os.Setenv("ALIAS_DB_PORT_NUMBER", "6543")
+		cleanupEnv(t, "ALIAS_DB_HOSTNAME", "DATABASE_HOST", "ALIAS_DB_PORT_NUMBER")
+
+		var deprecations []cfgx.Deprecation
+		var cfg aliasConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix:    "ALIAS",
+			SkipFlags:    true,
+			Strict:       true,
+			OnDeprecated: func(d cfgx.Deprecation) { deprecations = append(deprecations, d) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.DB.Host != "old.internal" || cfg.DB.Port != 6543 {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+
+		// Aliases are not deprecated
+		if len(deprecations) != 2 {
+			t.Fatalf("expected 2 deprecations, got %+v", deprecations)
+		}
+		want := cfgx.Deprecation{Field: "DB.Host", Kind: "env", Name: "ALIAS_DB_HOSTNAME", Replacement: "ALIAS_DB_HOST"}
+		if deprecations[0] != want {
+			t.Errorf("wanted %+v, got %+v", want, deprecations[0])
+		}
+		if deprecations[1].Name != "DATABASE_HOST" {
+			t.Errorf("expected DATABASE_HOST, got %+v", deprecations[1])
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		os.Setenv("ALIAS_DB_HOST", "new.internal")
+		os.Setenv("ALIAS_DB_HOSTNAME", "old.internal")
+		cleanupEnv(t, "ALIAS_DB_HOST", "ALIAS_DB_HOSTNAME")
+
+		var cfg aliasConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix:    "ALIAS",
+			SkipFlags:    true,
+			OnDeprecated: func(cfgx.Deprecation) {},
+		})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "env" || srcErr.Field != "DB.Host" {
+			t.Fatalf("expected env error for DB.Host, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "ALIAS_DB_HOSTNAME and ALIAS_DB_HOST are both set to different values") {
+			t.Errorf("unexpected message: %v", err)
+		}
+	})
+
+	t.Run("SameValue", func(t *testing.T) {
+		os.Setenv("ALIAS_DB_PORT", "6543")
+		os.Setenv("ALIAS_DB_PORT_NUMBER", "6543")
+		cleanupEnv(t, "ALIAS_DB_PORT", "ALIAS_DB_PORT_NUMBER")
+
+		var cfg aliasConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "ALIAS", SkipFlags: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DB.Port != 6543 {
+			t.Errorf("Port: wanted 6543, got %d", cfg.DB.Port)
+		}
+	})
+}
+
+func TestAlias_Flag(t *testing.T) {
+	t.Parallel()
+
+	for _, gnu := range []bool{false, true} {
+		var deprecations []cfgx.Deprecation
+		var cfg aliasConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:         []string{"--db-hostname", "old.internal", "--db-port-number=6543"},
+			SkipEnv:      true,
+			Strict:       true,
+			GNUFlags:     gnu,
+			OnDeprecated: func(d cfgx.Deprecation) { deprecations = append(deprecations, d) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.DB.Host != "old.internal" || cfg.DB.Port != 6543 {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+		want := []cfgx.Deprecation{{Field: "DB.Host", Kind: "flag", Name: "db-hostname", Replacement: "db-host"}}
+		if len(deprecations) != 1 || deprecations[0] != want[0] {
+			t.Errorf("wanted %+v, got %+v", want, deprecations)
+		}
+
+		var conflict aliasConfig
+		err = cfgx.Parse(&conflict, cfgx.Options{
+			Args:         []string{"--db-port=1", "--db-port-number=2"},
+			SkipEnv:      true,
+			GNUFlags:     gnu,
+			Output:       io.Discard,
+			OnDeprecated: func(cfgx.Deprecation) {},
+		})
+		if err == nil || !strings.Contains(err.Error(), "db-port-number and db-port are both set to different values") {
+			t.Errorf("expected a conflict, got %v", err)
+		}
+	}
+}
+
+func TestAlias_Secret(t *testing.T) {
+	t.Parallel()
+
+	var deprecations []cfgx.Deprecation
+	var cfg aliasConfig
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		SkipFlags: true,
+		SkipEnv:   true,
+		Sources: []cfgx.Source{&cfgx.FileContentSource{
+			PriorityLevel: cfgx.PrioritySecrets,
+			FS:            fstest.MapFS{"db_pass": {Data: []byte("s3cret\n")}},
+		}},
+		OnDeprecated: func(d cfgx.Deprecation) { deprecations = append(deprecations, d) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.DB.Password != "s3cret" {
+		t.Errorf("Password: wanted s3cret, got %q", cfg.DB.Password)
+	}
+	want := cfgx.Deprecation{Field: "DB.Password", Kind: "secret", Name: "db_pass", Replacement: "db_password"}
+	if len(deprecations) != 1 || deprecations[0] != want {
+		t.Errorf("wanted %+v, got %+v", want, deprecations)
+	}
+}
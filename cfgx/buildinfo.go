@@ -0,0 +1,186 @@
+package cfgx
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildInfo is the build metadata of the binary. Add a field of this
+// type to have Parse fill it from [debug.ReadBuildInfo], and to add a
+// --version flag that prints it. No source sets it, but its non-zero
+// fields are kept, e.g. a Version set with -ldflags.
+type BuildInfo struct {
+	Version   string            `json:"version"`            // Module version, or the top level Version field
+	Revision  string            `json:"revision,omitempty"` // VCS commit, e.g. a git hash
+	Time      time.Time         `json:"time,omitzero"`      // Time of the commit
+	Modified  bool              `json:"modified,omitempty"` // Built with uncommitted changes
+	GoVersion string            `json:"go_version"`         // Go toolchain, e.g. go1.24.0
+	Module    string            `json:"module"`             // Path of the main module
+	Settings  map[string]string `json:"settings,omitempty"` // The build settings in [BuildSettings]
+}
+
+// BuildSettings are the build settings copied into [BuildInfo.Settings].
+var BuildSettings = []string{"GOOS", "GOARCH", "CGO_ENABLED", "-tags", "-trimpath"}
+
+var buildInfoType = reflect.TypeFor[BuildInfo]()
+
+// versionFlag is the key of the --version flag in the flag values,
+// which can't be the path of an exported field.
+const versionFlag = "version"
+
+// ReadBuildInfo returns the build metadata of the binary. The version
+// is "(devel)" when there is no module version, e.g. with go run.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{Version: "(devel)"}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	info.Version = cmp.Or(bi.Main.Version, info.Version)
+	info.GoVersion = bi.GoVersion
+	info.Module = bi.Main.Path
+
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "vcs.revision":
+			info.Revision = s.Value
+		case s.Key == "vcs.time":
+			info.Time, _ = time.Parse(time.RFC3339, s.Value)
+		case s.Key == "vcs.modified":
+			info.Modified = s.Value == "true"
+		case slices.Contains(BuildSettings, s.Key):
+			if info.Settings == nil {
+				info.Settings = map[string]string{}
+			}
+			info.Settings[s.Key] = s.Value
+		}
+	}
+
+	return info
+}
+
+// String returns the version on the first line, followed by
+// a "name: value" line for each field that is set.
+func (b BuildInfo) String() string {
+	var sb strings.Builder
+	sb.WriteString(cmp.Or(b.Version, "(devel)"))
+
+	line := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "\n%s: %s", name, value)
+		}
+	}
+
+	revision := b.Revision
+	if b.Modified {
+		revision = strings.TrimSpace(revision + " (modified)")
+	}
+	line("revision", revision)
+	if !b.Time.IsZero() {
+		line("time", b.Time.Format(time.RFC3339))
+	}
+	line("go", b.GoVersion)
+	line("module", b.Module)
+	for _, key := range slices.Sorted(maps.Keys(b.Settings)) {
+		line(key, b.Settings[key])
+	}
+
+	return sb.String()
+}
+
+// hasBuildInfo reports whether the struct type has a [BuildInfo] field.
+func hasBuildInfo(t reflect.Type) bool {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		switch {
+		case !f.IsExported():
+		case f.Type == buildInfoType:
+			return true
+		case f.Type.Kind() == reflect.Struct && hasBuildInfo(f.Type):
+			return true
+		}
+	}
+	return false
+}
+
+// fillBuildInfo sets the zero fields of each [BuildInfo] in the struct,
+// using a top level Version string field for the version if it is set.
+// It returns the first one.
+func fillBuildInfo(v reflect.Value) (BuildInfo, bool) {
+	info := ReadBuildInfo()
+	if version := v.FieldByName("Version"); version.IsValid() && version.Kind() == reflect.String {
+		info.Version = cmp.Or(version.String(), info.Version)
+	}
+
+	var filled []BuildInfo
+	var fill func(v reflect.Value)
+	fill = func(v reflect.Value) {
+		for i := range v.NumField() {
+			f, field := v.Field(i), v.Type().Field(i)
+			switch {
+			case !field.IsExported():
+			case f.Type() == buildInfoType:
+				mergeZero(f, reflect.ValueOf(info))
+				filled = append(filled, f.Interface().(BuildInfo))
+			case f.Kind() == reflect.Struct:
+				fill(f)
+			}
+		}
+	}
+	fill(v)
+
+	if len(filled) == 0 {
+		return BuildInfo{}, false
+	}
+	return filled[0], true
+}
+
+// mergeZero sets the zero fields of the struct dst to those of src.
+func mergeZero(dst, src reflect.Value) {
+	for i := range dst.NumField() {
+		if dst.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+// newVersionFlag returns the flag value of --version.
+func newVersionFlag() *fieldFlag {
+	return &fieldFlag{field: ConfigField{
+		Path:        versionFlag,
+		Name:        versionFlag,
+		Kind:        reflect.Bool,
+		Value:       reflect.New(reflect.TypeFor[bool]()).Elem(),
+		Description: "Print the version and exit",
+		state:       &fieldState{},
+	}}
+}
+
+// hasVersionFlag reports whether the flag is set to true.
+func hasVersionFlag(f *fieldFlag) bool {
+	if f == nil || len(f.raw) == 0 {
+		return false
+	}
+	show, _ := strconv.ParseBool(f.raw[len(f.raw)-1])
+	return show
+}
+
+// usesFlagName reports whether a field has the flag name, or short name.
+func usesFlagName(fields map[string]ConfigField, name string) bool {
+	for _, field := range fields {
+		flagName, short := flagNames(field)
+		if flagName == name || short == name {
+			return true
+		}
+	}
+	return false
+}
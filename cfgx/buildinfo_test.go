@@ -0,0 +1,134 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type buildInfoConfig struct {
+	Port  int `default:"8080"`
+	Token string
+	Build cfgx.BuildInfo
+}
+
+func TestBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Fill", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := buildInfoConfig{Build: cfgx.BuildInfo{Revision: "abc123"}}
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{"--token", "x"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := cfgx.ReadBuildInfo()
+		if cfg.Build.Version != want.Version || cfg.Build.GoVersion != want.GoVersion || cfg.Build.Module != want.Module {
+			t.Errorf("wanted %+v, got %+v", want, cfg.Build)
+		}
+		// Set fields are kept
+		if cfg.Build.Revision != "abc123" {
+			t.Errorf("Revision: wanted abc123, got %s", cfg.Build.Revision)
+		}
+	})
+
+	t.Run("TopLevelVersion", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg struct {
+			Version string `default:"v1.2.3"`
+			Build   cfgx.BuildInfo
+		}
+		if err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{}}); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Build.Version != "v1.2.3" {
+			t.Errorf("Version: wanted v1.2.3, got %s", cfg.Build.Version)
+		}
+	})
+
+	t.Run("NotASource", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg buildInfoConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Strict: true, Args: []string{"--build-revision", "x"}, Output: &bytes.Buffer{}})
+		if err == nil || !strings.Contains(err.Error(), "build-revision") {
+			t.Errorf("expected an unknown flag error, got %v", err)
+		}
+	})
+}
+
+func TestVersionFlag(t *testing.T) {
+	t.Parallel()
+
+	for _, gnu := range []bool{false, true} {
+		var cfg buildInfoConfig
+		var buf bytes.Buffer
+
+		// The required Token is not needed for --version
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			ProgramName: "app",
+			Args:        []string{"--version"},
+			SkipEnv:     true,
+			GNUFlags:    gnu,
+			Output:      &buf,
+		})
+		if !errors.Is(err, cfgx.ErrVersion) {
+			t.Fatalf("wanted ErrVersion, got %v", err)
+		}
+
+		want := "app " + cfgx.ReadBuildInfo().String() + "\n"
+		if buf.String() != want {
+			t.Errorf("wanted %q, got %q", want, buf.String())
+		}
+	}
+
+	t.Run("Usage", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		if err := cfgx.WriteUsage(&buf, &buildInfoConfig{}, cfgx.Options{ProgramName: "app"}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "--version") || strings.Contains(buf.String(), "build") {
+			t.Errorf("unexpected usage:\n%s", buf.String())
+		}
+	})
+
+	t.Run("FieldNamedVersion", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg struct {
+			Version string `optional:"true"`
+			Build   cfgx.BuildInfo
+		}
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{"--version", "v2.0.0"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Version != "v2.0.0" || cfg.Build.Version != "v2.0.0" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+}
+
+func TestBuildInfo_String(t *testing.T) {
+	t.Parallel()
+
+	info := cfgx.BuildInfo{
+		Version:   "v1.2.3",
+		Revision:  "abc123",
+		Modified:  true,
+		GoVersion: "go1.24.0",
+		Settings:  map[string]string{"GOOS": "linux", "-tags": "prod"},
+	}
+	want := "v1.2.3\nrevision: abc123 (modified)\ngo: go1.24.0\n-tags: prod\nGOOS: linux"
+	if got := info.String(); got != want {
+		t.Errorf("wanted %q, got %q", want, got)
+	}
+}
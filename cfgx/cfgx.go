@@ -11,11 +11,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"maps"
 	"os"
 	"reflect"
-	"runtime/debug"
 	"slices"
 	"strings"
 )
@@ -27,6 +27,11 @@ const (
 	tagDescription = "desc"     // Description for help messages
 	tagOptional    = "optional" // Mark field as optional
 	tagShort       = "short"    // Short flag in addition
+	tagKey         = "key"      // Dotted key path in config files
+	tagSeparator   = "sep"      // Separator for slice and map values
+	tagSecret      = "secret"   // Redact the value in errors, reports and logs
+	tagResolve     = "resolve"  // Set to "true" to resolve references, or "false" to keep all values as is
+	tagArgs        = "args"     // Set to "true" to collect the positional args
 
 	tagDockerSecret = "dsec" // Optional
 )
@@ -35,6 +40,7 @@ const (
 // Higher priority sources override lower priority sources.
 const (
 	PriorityDefault = 0   // Default values from struct tags
+	PriorityFile    = 25  // Structured config files (JSON, YAML, TOML)
 	PriorityEnv     = 50  // Environment variables
 	PrioritySecrets = 75  // Docker secrets and other file-based secrets
 	PriorityFlags   = 100 // Command-line flags
@@ -42,6 +48,8 @@ const (
 
 var (
 	ErrNotPointerToStruct = errors.New("config must be a pointer to a struct")
+	// ErrVersion is returned after --version prints the [BuildInfo].
+	ErrVersion = errors.New("version requested")
 )
 
 // Source processes the configField map and applies values to the
@@ -67,20 +75,82 @@ type Options struct {
 	ErrorHandling flag.ErrorHandling
 	// Sources adds additional sources.
 	Sources []Source
+	// Decoders registers decoders by type for field types that do not
+	// implement encoding.TextUnmarshaler or flag.Value.
+	Decoders map[reflect.Type]DecodeFunc
+	// Provenance is filled with the source of each field's value if not nil.
+	Provenance *Provenance
+	// Output is where the usage for -h and --help is written (defaults to os.Stderr).
+	Output io.Writer
+	// StructValues keeps values already set in the struct as the lowest
+	// priority source, below default tags. The fields are skipped otherwise.
+	StructValues bool
+	// Resolvers registers resolvers by scheme for references such as
+	// "vault:secret/db", in addition to "file:" and "env:".
+	Resolvers map[string]ResolveFunc
+	// Decrypter decrypts values written as "enc:<base64>", e.g. a kv.AESEncryptor.
+	// Encrypt them with [EncryptValue] or the cfgxenc command.
+	Decrypter Decrypter
+	// Resolve resolves references such as "file:/run/secrets/db" and
+	// ${} interpolation in every field. Otherwise only fields tagged
+	// `resolve:"true"` are resolved.
+	Resolve bool
+	// Strict fails on unknown flags and on environment variables with
+	// the EnvPrefix that match no field. Unknown flags are ignored otherwise.
+	Strict bool
+	// GNUFlags parses flags like GNU tools instead of the flag package:
+	// --name and -n (with the "short" tag), bundled bool short flags (-vq),
+	// --no-name for bools, and positional args between the flags.
+	GNUFlags bool
+	// ProfileSources adds sources by profile, e.g. a config.prod.yaml
+	// file for "prod". The profile is the value of the field tagged
+	// `profile:"true"`, and its sources and "default.<profile>" tags
+	// override the sources with the same or a lower priority.
+	ProfileSources map[string][]Source
+	// OnDeprecated is called when a field is set with a name in its
+	// "deprecated" tag (defaults to a slog warning).
+	OnDeprecated func(Deprecation)
+	// Env replaces the process environment if not nil, e.g. for tests
+	// that run in parallel. It is used for the environment variables,
+	// env: and ${} references, and expanding .env files.
+	Env map[string]string
 }
 
 // Parse populates the config struct from different sources.
+// If the args contain -h or --help it writes the usage to
+// [Options.Output] and returns [flag.ErrHelp].
 // It follows this priority order (highest to lowest):
 //
 // Command line arguments - 100,
 // Environment variables - 50,
+// .env files - 40 (when added with [NewDotEnvSource]),
+// Config files - 25 (when added with [NewFileSource]),
 // Default values from struct tags - 0
 //
 // To add a source in order, choose a priority in between the
 // included sources.
 // Add a top level field named Version to read the build info
 // into it (as of 1.24 it uses the git tag).
+// Add a [BuildInfo] field for the rest of the build metadata
+// and a --version flag that prints it.
 func Parse(cfg any, options Options) error {
+	_, err := parse(cfg, options, parseParams{})
+	return err
+}
+
+// parseParams are the settings of parse that are not in [Options].
+type parseParams struct {
+	// usage writes the help for -h and --help (defaults to [WriteUsage]).
+	usage func(opts Options)
+	// ignoreEnv are prefixes of environment variables
+	// that are not unknown in strict mode.
+	ignoreEnv []string
+	// stopAtArgs ends parsing GNU flags at the first positional arg.
+	stopAtArgs bool
+}
+
+// parse is [Parse], returning the positional args left after the flags.
+func parse(cfg any, options Options, params parseParams) ([]string, error) {
 
 	// Set default options and override if non-zero
 	opts := setOptions(options)
@@ -88,15 +158,35 @@ func Parse(cfg any, options Options) error {
 	// Make sure it is pointer to struct
 	v := reflect.ValueOf(cfg)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
-		return handleError(opts.ErrorHandling, ErrNotPointerToStruct)
+		return nil, handleError(opts.ErrorHandling, ErrNotPointerToStruct)
 	}
 
 	// Walk the struct and get map of paths with dot notation
-	// Skips any fields that are already populated
-	structMap := walkStruct(v.Elem(), "")
+	structMap := walkStruct(v.Elem(), "", nil, opts.Decoders)
 
 	var sources []Source
 
+	// Keep the values already in the struct as the lowest priority,
+	// or skip those fields entirely. Skipped fields are still known
+	// names in strict mode.
+	var skipped map[string]ConfigField
+	if opts.StructValues {
+		sources = append(sources, &structSource{priority: PriorityDefault})
+	} else {
+		skipped = map[string]ConfigField{}
+		maps.DeleteFunc(structMap, func(path string, field ConfigField) bool {
+			if field.Value.IsZero() {
+				return false
+			}
+			skipped[path] = field
+			return true
+		})
+	}
+
+	// Set Version if exists in the structMap. Will be overridden
+	// if it exists in other sources.
+	sources = append(sources, &buildSource{priority: PriorityDefault})
+
 	// Set default tags source
 	sources = append(sources, &defaultSource{priority: PriorityDefault})
 
@@ -104,49 +194,148 @@ func Parse(cfg any, options Options) error {
 	if !opts.SkipEnv {
 		sources = append(sources, &envSource{
 			priority: PriorityEnv,
-			prefix:   options.EnvPrefix,
+			prefix:   opts.EnvPrefix,
+			strict:   opts.Strict,
+			ignore:   params.ignoreEnv,
+			env:      opts.Env,
+			skipped:  skipped,
 		})
 	}
 
-	//  Set command flags source
+	//  Set command flags source, which keeps the positional args
+	rest := opts.Args
+	flags := &flagSource{
+		priority: PriorityFlags,
+		opts:     opts,
+		usage:    func() { WriteUsage(opts.Output, cfg, opts) },
+		stop:     params.stopAtArgs,
+		version:  hasBuildInfo(v.Elem().Type()),
+		skipped:  skipped,
+	}
+	if params.usage != nil {
+		flags.usage = func() { params.usage(opts) }
+	}
 	if !opts.SkipFlags {
-		sources = append(sources, &flagSource{
-			priority: PriorityFlags,
-			opts:     options,
-		})
+		sources = append(sources, flags)
 	}
 
-	// Set the optional additional sources
-	if len(opts.Sources) > 0 {
-		sources = append(sources, opts.Sources...)
+	// Set the optional additional sources, using the same
+	// prefix for .env files as for the environment
+	for _, source := range opts.Sources {
+		sources = append(sources, withOptions(source, opts))
 	}
 
-	// Set Version if exists in the structMap. Will be overridden
-	// if it exists in other sources.
-	if version, ok := structMap["Version"]; ok {
-		bi, _ := debug.ReadBuildInfo()
+	// Sort and call Process on each source, keeping the order
+	// of sources with the same priority
+	slices.SortStableFunc(sources, func(a, b Source) int {
+		return cmp.Compare(a.Priority(), b.Priority())
+	})
 
-		version.Value.SetString(cmp.Or(bi.Main.Version, "(devel)"))
+	// Keep references in raw values to resolve after all sources
+	res := newResolver(opts.Resolvers, structMap, opts.Env)
+	if opts.Decrypter != nil {
+		res.schemes[encPrefix] = DecryptResolver(opts.Decrypter)
+	}
+	for _, field := range structMap {
+		switch field.Tag.Get(tagResolve) {
+		case "false":
+		case "true":
+			field.state.resolver, field.state.refs = res, true
+		default:
+			field.state.resolver, field.state.refs = res, opts.Resolve
+		}
 	}
 
-	// Sort and call Process on each source
-	slices.SortFunc(sources, func(a, b Source) int {
-		return cmp.Compare(a.Priority(), b.Priority())
-	})
+	// Report deprecated names to the callback
+	for _, field := range structMap {
+		field.state.onDeprecated = opts.OnDeprecated
+	}
 
+	// Collect the errors of all sources, annotated with the source name
+	var allErrs []error
+
+	trace := newTracer()
 	for _, source := range sources {
-		source.Process(structMap)
+		err := trace.process(source, structMap)
+
+		// Stop after printing the usage for -h and --help
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, handleHelp(opts.ErrorHandling, flag.ErrHelp)
+		}
+
+		allErrs = append(allErrs, sourceErrors(source, err)...)
+	}
+	if !opts.SkipFlags {
+		rest = flags.rest
+	}
+
+	// Layer the sources of the profile selected by the sources above
+	profile := activeProfile(structMap)
+	if profile != "" {
+		for _, source := range profileSources(profile, opts) {
+			err := trace.processProfile(withOptions(source, opts), structMap)
+			allErrs = append(allErrs, sourceErrors(source, err)...)
+		}
+	}
+
+	// Resolve references, annotated with the source of the raw value
+	for _, err := range res.resolveAll() {
+		allErrs = append(allErrs, &SourceError{Source: trace.last(err.path), Field: err.path, Err: err.err})
 	}
 
-	// Validate the required
-	if err := validateRequired(structMap); err != nil {
-		return handleError(opts.ErrorHandling, fmt.Errorf("validation: %w", err))
+	// Fill the build info, and print it for --version
+	// even if the config is not valid
+	if info, ok := fillBuildInfo(v.Elem()); ok && flags.showVersion {
+		fmt.Fprintf(opts.Output, "%s %s\n", opts.ProgramName, info)
+		return nil, handleHelp(opts.ErrorHandling, ErrVersion)
 	}
 
-	return nil
+	if opts.Provenance != nil {
+		*opts.Provenance = trace.provenance(structMap)
+		opts.Provenance.Profile = profile
+	}
+
+	// Fields set by a source their sources tag does not allow
+	slices.SortStableFunc(trace.disallowed, func(a, b error) int {
+		return strings.Compare(a.(*ValidationError).Field, b.(*ValidationError).Field)
+	})
+	allErrs = append(allErrs, trace.disallowed...)
+
+	// Validate the required fields and the validation tags.
+	// A field a source failed to set is not also reported as required.
+	for _, err := range validateRequired(structMap) {
+		if !hasFieldError(allErrs, err.(*ValidationError).Field) {
+			allErrs = append(allErrs, err)
+		}
+	}
+	allErrs = append(allErrs, validateTags(structMap)...)
+	allErrs = append(allErrs, validateStructs(v.Elem(), "", opts.Decoders)...)
+
+	if len(allErrs) > 0 {
+		return rest, handleError(opts.ErrorHandling, &MultiError{allErrs})
+	}
+
+	return rest, nil
+}
+
+// withOptions uses the env prefix for a [DotEnvSource] without one,
+// and [Options.Env] to expand its variables, without changing the
+// source passed in.
+func withOptions(source Source, opts Options) Source {
+	switch s := source.(type) {
+	case *DotEnvSource:
+		withOpts := *s
+		withOpts.EnvPrefix = cmp.Or(s.EnvPrefix, opts.EnvPrefix)
+		withOpts.env = opts.Env
+		return &withOpts
+	case *profileSource:
+		return &profileSource{Source: withOptions(s.Source, opts), profile: s.profile}
+	}
+	return source
 }
 
 // ConfigField represents a field in the config struct.
+// Use [ConfigField.Set] to parse a raw value into it.
 type ConfigField struct {
 	Path        string
 	Value       reflect.Value
@@ -155,10 +344,14 @@ type ConfigField struct {
 	StructField reflect.StructField
 	Tag         reflect.StructTag
 	Description string
+
+	decoders map[reflect.Type]DecodeFunc
+	state    *fieldState
+	index    []int // Index sequence from the root, for declaration order
 }
 
 // Gather map of ConfigFields
-func walkStruct(v reflect.Value, currPath string) map[string]ConfigField {
+func walkStruct(v reflect.Value, currPath string, currIndex []int, decoders map[reflect.Type]DecodeFunc) map[string]ConfigField {
 	fields := map[string]ConfigField{}
 
 	t := v.Type()
@@ -171,8 +364,8 @@ func walkStruct(v reflect.Value, currPath string) map[string]ConfigField {
 		kind := fieldVal.Kind()
 		tag := structField.Tag
 
-		// Skip fields already filled
-		if !fieldVal.IsZero() {
+		// Skip unexported fields
+		if !structField.IsExported() {
 			continue
 		}
 
@@ -182,44 +375,50 @@ func walkStruct(v reflect.Value, currPath string) map[string]ConfigField {
 			path = strings.Join([]string{currPath, name}, ".")
 		}
 
-		// Recursive for structs
-		if kind == reflect.Struct {
-			nestedFields := walkStruct(fieldVal, path)
+		// Skip the build info, which Parse fills after the sources
+		if structField.Type == buildInfoType {
+			continue
+		}
+
+		index := append(slices.Clone(currIndex), i)
+
+		// Recursive for structs, unless decoded as a whole (e.g. time.Time)
+		if kind == reflect.Struct && !hasDecoder(fieldVal.Type(), decoders) {
+			nestedFields := walkStruct(fieldVal, path, index, decoders)
 			maps.Copy(fields, nestedFields)
 			continue
 		}
 		desc := cmp.Or(tag.Get(tagDescription), path)
 
 		fields[path] = ConfigField{
-			Path: path, Value: fieldVal, Kind: kind, Name: name, StructField: structField, Tag: tag, Description: desc}
+			Path: path, Value: fieldVal, Kind: kind, Name: name, StructField: structField, Tag: tag, Description: desc,
+			decoders: decoders,
+			state:    &fieldState{},
+			index:    index,
+		}
 	}
 	return fields
 }
 
-// Error if required fields are missing
-func validateRequired(fields map[string]ConfigField) error {
-	var allErrs []error
-
-	for path, field := range fields {
-		// Get optional tag
-		reqVal, exists := field.Tag.Lookup(tagOptional)
-
-		// Skip if optional
-		optional := exists && reqVal != "false"
-		if optional {
-			continue
-		}
+// orderedFields returns the fields in declaration order.
+func orderedFields(fields map[string]ConfigField) []ConfigField {
+	ordered := slices.Collect(maps.Values(fields))
+	slices.SortFunc(ordered, func(a, b ConfigField) int {
+		return slices.Compare(a.index, b.index)
+	})
+	return ordered
+}
 
-		// If it is required and zero value add error
-		if field.Value.IsZero() {
-			allErrs = append(allErrs, fmt.Errorf("%s is required", path))
-		}
+// Handle -h, --help and --version like the flag package
+func handleHelp(errHandling flag.ErrorHandling, err error) error {
+	if errHandling == flag.ExitOnError {
+		os.Exit(0)
 	}
-
-	if len(allErrs) > 0 {
-		return &MultiError{allErrs}
+	if errHandling == flag.PanicOnError {
+		panic(err)
 	}
-	return nil
+
+	return err
 }
 
 // Handle the errors depending on the strategy
@@ -0,0 +1,125 @@
+// Package cfgxtest helps test code that parses config with cfgx. It
+// isolates [cfgx.Parse] from the process environment, args and secret
+// files, so tests can run in parallel, and checks which source
+// provided each field:
+//
+//	func TestConfig(t *testing.T) {
+//		t.Parallel()
+//
+//		var cfg Config
+//		prov := cfgxtest.Parse(t, &cfg, cfgx.Options{
+//			EnvPrefix: "APP",
+//			Env:       map[string]string{"APP_PORT": "9000"},
+//			Args:      []string{"--debug"},
+//			Sources:   []cfgx.Source{cfgxtest.Secrets(fstest.MapFS{"db_password": {Data: []byte("s3cret")}})},
+//		})
+//
+//		cfgxtest.AssertSource(t, prov, "Port", "env")
+//		cfgxtest.AssertSource(t, prov, "DB.Password", "secret")
+//	}
+package cfgxtest
+
+import (
+	"io"
+	"io/fs"
+	"slices"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+// Isolate returns the options with an empty environment and no args
+// unless they are set, and the usage and errors written to [io.Discard]
+// unless Output is set.
+func Isolate(opts cfgx.Options) cfgx.Options {
+	if opts.Env == nil {
+		opts.Env = map[string]string{}
+	}
+	if opts.Args == nil {
+		opts.Args = []string{}
+	}
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	return opts
+}
+
+// Parse parses the config with the options isolated by [Isolate],
+// failing the test on an error. It returns the provenance of the
+// fields for the assertions.
+func Parse(t testing.TB, cfg any, opts cfgx.Options) *cfgx.Provenance {
+	t.Helper()
+
+	prov := opts.Provenance
+	if prov == nil {
+		prov = &cfgx.Provenance{}
+	}
+	opts.Provenance = prov
+
+	if err := cfgx.Parse(cfg, Isolate(opts)); err != nil {
+		t.Fatalf("cfgx.Parse: %v", err)
+	}
+	return prov
+}
+
+// Secrets returns a [cfgx.DockerSecretsSource] that reads the secret
+// files from fsys, e.g. an [fstest.MapFS], instead of /run/secrets.
+func Secrets(fsys fs.FS) *cfgx.DockerSecretsSource {
+	s := cfgx.NewDockerSecretsSource()
+	s.FS = fsys
+	return s
+}
+
+// AssertSource checks that the named source, e.g. "env" or "file",
+// provided the final value of the field at the dotted path.
+func AssertSource(t testing.TB, prov *cfgx.Provenance, path, source string) {
+	t.Helper()
+
+	f, ok := field(t, prov, path)
+	switch {
+	case !ok:
+	case f.Source == nil:
+		t.Errorf("%s: wanted source %s, but no source set it", path, source)
+	case f.Source.Source != source:
+		t.Errorf("%s: wanted source %s, got %s", path, source, f.Source.Source)
+	}
+}
+
+// AssertOverridden checks the sources whose values for the field at
+// the dotted path were overridden, from lowest to highest priority.
+func AssertOverridden(t testing.TB, prov *cfgx.Provenance, path string, sources ...string) {
+	t.Helper()
+
+	f, ok := field(t, prov, path)
+	if !ok {
+		return
+	}
+
+	var got []string
+	for _, o := range f.Overridden {
+		got = append(got, o.Source)
+	}
+	if !slices.Equal(got, sources) {
+		t.Errorf("%s: wanted overridden sources %v, got %v", path, sources, got)
+	}
+}
+
+// AssertUnset checks that no source set the field at the dotted path.
+func AssertUnset(t testing.TB, prov *cfgx.Provenance, path string) {
+	t.Helper()
+
+	if f, ok := field(t, prov, path); ok && f.Source != nil {
+		t.Errorf("%s: wanted no source, got %s", path, f.Source.Source)
+	}
+}
+
+// field returns the provenance of the field, reporting a missing one.
+func field(t testing.TB, prov *cfgx.Provenance, path string) (cfgx.FieldProvenance, bool) {
+	t.Helper()
+
+	f, ok := prov.Field(path)
+	if !ok {
+		t.Errorf("%s: no such field in the provenance", path)
+	}
+	return f, ok
+}
@@ -0,0 +1,103 @@
+package cfgxtest_test
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+	"github.com/erlorenz/go-toolbox/cfgx/cfgxtest"
+)
+
+type testConfig struct {
+	Port  int    `default:"8080"`
+	Host  string `default:"localhost"`
+	Debug bool   `optional:"true"`
+	URL   string `default:"http://${Host}:${Port}"`
+	DB    struct {
+		Password string `secret:"true"`
+	}
+	Token string `optional:"true"`
+}
+
+// recorder records the failures of the assertions.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	var cfg testConfig
+	prov := cfgxtest.Parse(t, &cfg, cfgx.Options{
+		EnvPrefix: "APP",
+		Env:       map[string]string{"APP_PORT": "9000", "APP_HOST": "${SERVICE_HOST}", "SERVICE_HOST": "example.com"},
+		Args:      []string{"--debug"},
+		Resolve:   true,
+		Sources: []cfgx.Source{
+			cfgxtest.Secrets(fstest.MapFS{"db_password": {Data: []byte("s3cret\n")}}),
+		},
+	})
+
+	if cfg.Port != 9000 || cfg.Host != "example.com" || !cfg.Debug || cfg.DB.Password != "s3cret" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cfg.URL != "http://example.com:9000" {
+		t.Errorf("URL: wanted http://example.com:9000, got %s", cfg.URL)
+	}
+
+	cfgxtest.AssertSource(t, prov, "Port", "env")
+	cfgxtest.AssertOverridden(t, prov, "Port", "default")
+	cfgxtest.AssertSource(t, prov, "Debug", "flag")
+	cfgxtest.AssertSource(t, prov, "DB.Password", "secret")
+	cfgxtest.AssertSource(t, prov, "URL", "default")
+	cfgxtest.AssertUnset(t, prov, "Token")
+}
+
+func TestIsolate(t *testing.T) {
+	t.Setenv("ISOLATE_TOKEN", "from-process")
+
+	var cfg testConfig
+	err := cfgx.Parse(&cfg, cfgxtest.Isolate(cfgx.Options{EnvPrefix: "ISOLATE"}))
+	if err == nil {
+		t.Fatal("expected an error for the missing password")
+	}
+	if cfg.Token != "" {
+		t.Errorf("Token: expected the process environment to be ignored, got %s", cfg.Token)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	t.Parallel()
+
+	var cfg testConfig
+	prov := cfgxtest.Parse(t, &cfg, cfgx.Options{
+		Args:    []string{"--token", "x"},
+		Sources: []cfgx.Source{cfgxtest.Secrets(fstest.MapFS{"db_password": {Data: []byte("s3cret")}})},
+	})
+
+	r := &recorder{}
+	cfgxtest.AssertSource(r, prov, "Port", "env")
+	cfgxtest.AssertSource(r, prov, "Debug", "flag")
+	cfgxtest.AssertOverridden(r, prov, "Token", "env")
+	cfgxtest.AssertUnset(r, prov, "Token")
+	cfgxtest.AssertUnset(r, prov, "Missing")
+
+	want := []string{
+		"Port: wanted source env, got default",
+		"Debug: wanted source flag, but no source set it",
+		"Token: wanted overridden sources [env], got []",
+		"Token: wanted no source, got flag",
+		"Missing: no such field in the provenance",
+	}
+	if fmt.Sprint(r.errs) != fmt.Sprint(want) {
+		t.Errorf("wanted %q, got %q", want, r.errs)
+	}
+}
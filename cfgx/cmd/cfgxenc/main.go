@@ -0,0 +1,78 @@
+// Command cfgxenc encrypts a config value into the "enc:<base64>" format
+// that cfgx decrypts with [cfgx.Options.Decrypter], using AES-256-GCM
+// from the kv package.
+//
+// The key is read from the CFGX_KEY environment variable as 32 base64
+// encoded bytes, and the value from the arguments or stdin:
+//
+//	export CFGX_KEY=$(openssl rand -base64 32)
+//	cfgxenc 's3cret'          # enc:...
+//	cfgxenc -d 'enc:...'      # s3cret
+//	cfgxenc < password.txt
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+	"github.com/erlorenz/go-toolbox/kv"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cfgxenc: ")
+
+	keyEnv := flag.String("key-env", "CFGX_KEY", "environment variable with the base64 encoded 32 byte key")
+	decrypt := flag.Bool("d", false, "decrypt an enc: value instead")
+	flag.Parse()
+
+	key, err := base64.StdEncoding.DecodeString(os.Getenv(*keyEnv))
+	if err != nil {
+		log.Fatalf("decode key from %s: %v", *keyEnv, err)
+	}
+
+	enc, err := kv.NewAESEncryptor(key)
+	if err != nil {
+		log.Fatalf("key from %s: %v", *keyEnv, err)
+	}
+
+	value, err := readValue(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *decrypt {
+		plaintext, err := cfgx.DecryptResolver(enc)(strings.TrimPrefix(value, "enc:"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(plaintext)
+		return
+	}
+
+	out, err := cfgx.EncryptValue(context.Background(), enc, []byte(value))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(out)
+}
+
+// readValue joins the arguments, or reads stdin without the trailing newline.
+func readValue(args []string) (string, error) {
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("read stdin: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,123 @@
+// Command cfgxgen writes the JSON Schema, .env example and sample YAML
+// of a config struct with [cfgx.Generate], for use with go generate.
+// Next to the config type:
+//
+//	//go:generate go run github.com/erlorenz/go-toolbox/cfgx/cmd/cfgxgen -type Config -env-prefix APP -schema config.schema.json -env .env.example -yaml config.example.yaml
+//
+// It builds and runs a temporary program that imports the package in
+// the current directory, so the type can't be in a main package. The
+// file paths are relative to the current directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/token"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+var program = template.Must(template.New("main").Parse(`// Code generated by cfgxgen. DO NOT EDIT.
+
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+	config {{printf "%q" .Import}}
+)
+
+func main() {
+	err := cfgx.Generate(&config.{{.Type}}{}, cfgx.Options{EnvPrefix: {{printf "%q" .EnvPrefix}}}, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+`))
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("cfgxgen: ")
+
+	typeName := flag.String("type", "", "name of the config struct `type` in the current package")
+	envPrefix := flag.String("env-prefix", "", "environment variable `prefix`, as in cfgx.Options")
+	schema := flag.String("schema", "", "write the JSON Schema to `file`")
+	env := flag.String("env", "", "write the .env example to `file`")
+	yamlFile := flag.String("yaml", "", "write the YAML example to `file`")
+	flag.Parse()
+
+	if !token.IsIdentifier(*typeName) || !token.IsExported(*typeName) {
+		log.Fatalf("-type must be an exported type name, got %q", *typeName)
+	}
+
+	pkg, err := importPath()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var src bytes.Buffer
+	data := struct{ Import, Type, EnvPrefix string }{pkg, *typeName, *envPrefix}
+	if err := program.Execute(&src, data); err != nil {
+		log.Fatal(err)
+	}
+
+	// Pass only the files that were set, so Generate skips the others
+	var args []string
+	for _, f := range []struct{ name, path string }{{"schema", *schema}, {"env", *env}, {"yaml", *yamlFile}} {
+		if f.path != "" {
+			args = append(args, "-"+f.name, f.path)
+		}
+	}
+	if len(args) == 0 {
+		log.Fatal("no files to write, set -schema, -env or -yaml")
+	}
+
+	if err := run(src.Bytes(), args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// importPath returns the import path of the package in the current directory.
+func importPath() (string, error) {
+	out, err := exec.Command("go", "list", "-f", "{{.ImportPath}} {{.Name}}", ".").Output()
+	if err != nil {
+		return "", fmt.Errorf("go list: %w", err)
+	}
+
+	path, name, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+	if name == "main" {
+		return "", fmt.Errorf("%s is a main package, which can't be imported; move the config type to its own package", path)
+	}
+	return path, nil
+}
+
+// run writes the program to a temporary directory in the current one,
+// so it is in the same module and can import internal packages, and
+// runs it with the args.
+func run(src []byte, args []string) error {
+	dir, err := os.MkdirTemp(".", "_cfgxgen")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, src, 0o644); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", append([]string{"run", file}, args...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cfgx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// Command is a subcommand with its own config struct, e.g. "serve" or
+// "migrate", selected by the first positional argument.
+type Command struct {
+	// Name is the positional argument that selects the command.
+	Name string
+	// Description is shown in the list of commands.
+	Description string
+	// Config is a pointer to the command's config struct.
+	Config any
+	// EnvPrefix is the prefix of the command's environment variables
+	// (defaults to [Options.EnvPrefix] and the upper case name, e.g. APP_SERVE).
+	EnvPrefix string
+	// Sources are the additional sources of the command's config.
+	// The sources and profile sources in [Options] are only used for the globals.
+	Sources []Source
+}
+
+// envPrefix is the EnvPrefix or the global prefix and the name.
+func (c *Command) envPrefix(global string) string {
+	if c.EnvPrefix != "" {
+		return c.EnvPrefix
+	}
+	name := strings.ToUpper(strings.ReplaceAll(c.Name, "-", "_"))
+	if global == "" {
+		return name
+	}
+	return global + "_" + name
+}
+
+// ParseCommand parses the global config from the flags before the first
+// positional argument, which selects the command, and the command's
+// config from the rest of the args. It returns the command and the
+// positional args after its flags.
+//
+//	app --log-level debug serve --port 8080 extra args
+//
+// Global fields use [Options.EnvPrefix] and the command's fields its
+// own prefix, e.g. APP_LOG_LEVEL and APP_SERVE_PORT. The help for -h
+// lists the commands, and "app serve -h" shows the command's help.
+// The sources and profile sources in [Options] are only used for the
+// globals, so a command's config has no profile layering; add its
+// sources with [Command.Sources].
+// A missing or unknown command is an [ErrUnknownCommand].
+func ParseCommand(global any, options Options, commands ...Command) (*Command, []string, error) {
+	opts := setOptions(options)
+
+	// The command's variables are not unknown to the globals in strict mode
+	var prefixes []string
+	for _, cmd := range commands {
+		prefixes = append(prefixes, cmd.envPrefix(opts.EnvPrefix))
+	}
+
+	rest, err := parse(global, options, parseParams{
+		usage: func(opts Options) {
+			WriteUsage(opts.Output, global, opts)
+			writeCommands(opts.Output, opts.ProgramName, commands)
+		},
+		ignoreEnv:  prefixes,
+		stopAtArgs: true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(rest) == 0 {
+		err := fmt.Errorf("%w: missing command, expected one of %s", ErrUnknownCommand, commandNames(commands))
+		return nil, nil, handleError(opts.ErrorHandling, err)
+	}
+
+	cmd := findCommand(commands, rest[0])
+	if cmd == nil {
+		err := fmt.Errorf("%w %q, expected one of %s", ErrUnknownCommand, rest[0], commandNames(commands))
+		return nil, nil, handleError(opts.ErrorHandling, err)
+	}
+
+	cmdOpts := options
+	cmdOpts.ProgramName = opts.ProgramName + " " + cmd.Name
+	cmdOpts.EnvPrefix = cmd.envPrefix(opts.EnvPrefix)
+	cmdOpts.Args = rest[1:]
+	cmdOpts.Sources = cmd.Sources
+	cmdOpts.ProfileSources = nil
+	cmdOpts.Provenance = nil
+
+	rest, err = parse(cmd.Config, cmdOpts, parseParams{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cmd, rest, nil
+}
+
+func findCommand(commands []Command, name string) *Command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func commandNames(commands []Command) string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeCommands writes the list of commands after the usage.
+func writeCommands(w io.Writer, program string, commands []Command) {
+	fmt.Fprintf(w, "\nCommands:\n\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name, cmd.Description)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the options of a command.\n", program)
+}
@@ -0,0 +1,239 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type globalConfig struct {
+	LogLevel string `default:"info" desc:"Log level"`
+	Verbose  bool   `optional:"true" short:"v"`
+}
+
+type serveConfig struct {
+	Port int    `default:"8080" desc:"Port to listen on"`
+	Host string `default:"localhost"`
+}
+
+type migrateConfig struct {
+	Steps int `optional:"true"`
+}
+
+func commands(serve *serveConfig, migrate *migrateConfig) []cfgx.Command {
+	return []cfgx.Command{
+		{Name: "serve", Description: "Run the HTTP server", Config: serve},
+		{Name: "migrate", Description: "Run database migrations", Config: migrate},
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	os.Setenv("CMD_LOG_LEVEL", "debug")
+	os.Setenv("CMD_SERVE_HOST", "0.0.0.0")
+	cleanupEnv(t, "CMD_LOG_LEVEL", "CMD_SERVE_HOST")
+
+	var global globalConfig
+	var serve serveConfig
+	var migrate migrateConfig
+
+	cmd, args, err := cfgx.ParseCommand(&global, cfgx.Options{
+		EnvPrefix: "CMD",
+		Args:      []string{"-v", "serve", "--port", "9000", "extra", "args"},
+	}, commands(&serve, &migrate)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Name != "serve" {
+		t.Errorf("expected serve, got %s", cmd.Name)
+	}
+	if !slices.Equal(args, []string{"extra", "args"}) {
+		t.Errorf("expected the positional args, got %v", args)
+	}
+	if global.LogLevel != "debug" || !global.Verbose {
+		t.Errorf("unexpected globals: %+v", global)
+	}
+	if serve.Port != 9000 || serve.Host != "0.0.0.0" {
+		t.Errorf("unexpected serve config: %+v", serve)
+	}
+}
+
+func TestParseCommand_UnknownFlag(t *testing.T) {
+	for _, gnu := range []bool{false, true} {
+		var global globalConfig
+		var serve serveConfig
+		var migrate migrateConfig
+
+		// A global flag after the command is unknown to it
+		_, _, err := cfgx.ParseCommand(&global, cfgx.Options{
+			Args:     []string{"serve", "--log-level", "debug", "--port", "9000"},
+			SkipEnv:  true,
+			GNUFlags: gnu,
+		}, commands(&serve, &migrate)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if serve.Port != 9000 {
+			t.Errorf("GNUFlags %t: Port: wanted 9000, got %d", gnu, serve.Port)
+		}
+	}
+}
+
+func TestParseCommand_ProfileSources(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "config.prod.yaml")
+	if err := os.WriteFile(file, []byte("log_level: warn\nport: 9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var global struct {
+		Env      string `profile:"true" default:"prod"`
+		LogLevel string `default:"info"`
+	}
+	var serve struct {
+		Env  string `profile:"true" default:"prod"`
+		Port int    `default:"8080"`
+	}
+
+	// The profile's sources are only used for the globals, even when
+	// the command selects the same profile
+	_, _, err := cfgx.ParseCommand(&global, cfgx.Options{
+		Args:           []string{"serve"},
+		SkipEnv:        true,
+		ProfileSources: map[string][]cfgx.Source{"prod": {cfgx.NewFileSource(file)}},
+	}, cfgx.Command{Name: "serve", Config: &serve})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global.LogLevel != "warn" {
+		t.Errorf("LogLevel: wanted warn, got %s", global.LogLevel)
+	}
+	if serve.Port != 8080 {
+		t.Errorf("Port: wanted 8080, got %d", serve.Port)
+	}
+}
+
+func TestParseCommand_Strict(t *testing.T) {
+	os.Setenv("STRICTCMD_SERVE_PORT", "9000")
+	cleanupEnv(t, "STRICTCMD_SERVE_PORT")
+
+	var global globalConfig
+	var serve serveConfig
+	var migrate migrateConfig
+
+	// The command's variables are not unknown to the globals
+	_, _, err := cfgx.ParseCommand(&global, cfgx.Options{
+		EnvPrefix: "STRICTCMD",
+		Args:      []string{"serve"},
+		Strict:    true,
+	}, commands(&serve, &migrate)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serve.Port != 9000 {
+		t.Errorf("Port: wanted 9000, got %d", serve.Port)
+	}
+
+	// Command flags before the command are unknown
+	_, _, err = cfgx.ParseCommand(&global, cfgx.Options{
+		Args:    []string{"--port", "9000", "serve"},
+		SkipEnv: true,
+		Strict:  true,
+		Output:  &bytes.Buffer{},
+	}, commands(&serve, &migrate)...)
+	if err == nil {
+		t.Error("expected an error for a command flag before the command")
+	}
+}
+
+func TestParseCommand_Unknown(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]string{
+		"Missing": {"-v"},
+		"Unknown": {"deploy"},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var global globalConfig
+			var serve serveConfig
+			var migrate migrateConfig
+
+			_, _, err := cfgx.ParseCommand(&global, cfgx.Options{Args: args, SkipEnv: true}, commands(&serve, &migrate)...)
+			if !errors.Is(err, cfgx.ErrUnknownCommand) {
+				t.Fatalf("expected ErrUnknownCommand, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "serve, migrate") {
+				t.Errorf("expected the commands in the error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseCommand_Help(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Global", func(t *testing.T) {
+		t.Parallel()
+
+		var global globalConfig
+		var serve serveConfig
+		var migrate migrateConfig
+		var out bytes.Buffer
+
+		_, _, err := cfgx.ParseCommand(&global, cfgx.Options{
+			ProgramName: "app",
+			Args:        []string{"-h"},
+			SkipEnv:     true,
+			Output:      &out,
+		}, commands(&serve, &migrate)...)
+		if !errors.Is(err, flag.ErrHelp) {
+			t.Fatalf("expected flag.ErrHelp, got %v", err)
+		}
+
+		for _, want := range []string{"Usage of app:", "--log-level", "Commands:", "serve", "Run the HTTP server", "migrate", "app <command> -h"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("expected %q in:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("Command", func(t *testing.T) {
+		t.Parallel()
+
+		var global globalConfig
+		var serve serveConfig
+		var migrate migrateConfig
+		var out bytes.Buffer
+
+		_, _, err := cfgx.ParseCommand(&global, cfgx.Options{
+			ProgramName: "app",
+			EnvPrefix:   "APP",
+			Args:        []string{"serve", "--help"},
+			Output:      &out,
+		}, commands(&serve, &migrate)...)
+		if !errors.Is(err, flag.ErrHelp) {
+			t.Fatalf("expected flag.ErrHelp, got %v", err)
+		}
+
+		for _, want := range []string{"Usage of app serve:", "--port", "APP_SERVE_PORT", "Port to listen on"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("expected %q in:\n%s", want, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "--log-level") {
+			t.Errorf("expected only the command's flags in:\n%s", out.String())
+		}
+	})
+}
@@ -2,7 +2,10 @@ package cfgx_test
 
 import (
 	"flag"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -261,6 +264,37 @@ func TestOptions(t *testing.T) {
 			t.Errorf("Version: wanted %s, got %s", want, cfg.Version)
 		}
 	})
+
+	t.Run("Env", func(t *testing.T) {
+		// Only the variables in Env are used, for the environment,
+		// references and .env files, and unknown in strict mode
+		var cfg bicfg
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix: "OPTENV",
+			Args:      []string{},
+			Strict:    true,
+			Resolve:   true,
+			Env: map[string]string{
+				"OPTENV_PORT":     "env:OPTENV_API_PORT",
+				"OPTENV_API_PORT": "9000",
+				"OPTENV_TYPO":     "x",
+				"DOMAIN":          "example.org",
+			},
+			Sources: []cfgx.Source{&cfgx.DotEnvSource{
+				PriorityLevel: cfgx.PriorityDotEnv,
+				Paths:         []string{".env"},
+				FS:            fstest.MapFS{".env": {Data: []byte("API_URL=https://${DOMAIN}\n")}},
+			}},
+		})
+		if err == nil || strings.Count(err.Error(), "unknown environment variable") != 2 ||
+			!strings.Contains(err.Error(), "OPTENV_API_PORT") || !strings.Contains(err.Error(), "OPTENV_TYPO") {
+			t.Errorf("expected OPTENV_API_PORT and OPTENV_TYPO to be unknown, got %v", err)
+		}
+
+		if cfg.Port != 9000 || cfg.BaseURL != "https://example.org" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
 }
 
 func TestValidate(t *testing.T) {
@@ -403,3 +437,294 @@ func TestTypeSupport(t *testing.T) {
 		}
 	})
 }
+
+func TestCollectionSupport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Slice_Default", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Origins []string `default:"http://a.com, http://b.com"`
+			Ports   []int    `default:"80;443" sep:";"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []string{"http://a.com", "http://b.com"}; !slices.Equal(cfg.Origins, want) {
+			t.Errorf("Origins: wanted %v, got %v", want, cfg.Origins)
+		}
+		if want := []int{80, 443}; !slices.Equal(cfg.Ports, want) {
+			t.Errorf("Ports: wanted %v, got %v", want, cfg.Ports)
+		}
+	})
+
+	t.Run("Slice_Env", func(t *testing.T) {
+		var cfg struct {
+			BrokerHosts []string `sep:" "`
+		}
+
+		os.Setenv("BROKER_HOSTS", "kafka-1:9092 kafka-2:9092")
+		cleanupEnv(t, "BROKER_HOSTS")
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []string{"kafka-1:9092", "kafka-2:9092"}; !slices.Equal(cfg.BrokerHosts, want) {
+			t.Errorf("BrokerHosts: wanted %v, got %v", want, cfg.BrokerHosts)
+		}
+	})
+
+	t.Run("Slice_RepeatedFlags", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Origin []string `short:"o"`
+		}
+
+		args := []string{"--origin", "a", "-o", "b,c", "--origin=d"}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: args, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []string{"a", "b", "c", "d"}; !slices.Equal(cfg.Origin, want) {
+			t.Errorf("Origin: wanted %v, got %v", want, cfg.Origin)
+		}
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Labels  map[string]string `default:"team=core,env=dev"`
+			Weights map[string]int
+		}
+
+		args := []string{"--weights", "a=1", "--weights", "b=2"}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: args, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := map[string]string{"team": "core", "env": "dev"}; !maps.Equal(cfg.Labels, want) {
+			t.Errorf("Labels: wanted %v, got %v", want, cfg.Labels)
+		}
+		if want := map[string]int{"a": 1, "b": 2}; !maps.Equal(cfg.Weights, want) {
+			t.Errorf("Weights: wanted %v, got %v", want, cfg.Weights)
+		}
+	})
+
+	t.Run("Map_Invalid", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Labels map[string]string `optional:"true"`
+		}
+
+		cfgx.Parse(&cfg, cfgx.Options{Args: []string{"--labels", "novalue"}, SkipEnv: true})
+
+		if cfg.Labels != nil {
+			t.Errorf("Labels: wanted nil, got %v", cfg.Labels)
+		}
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		t.Parallel()
+		fakeFS := fstest.MapFS{
+			"allowed_addrs": &fstest.MapFile{Data: []byte("10.0.0.1,10.0.0.2\n")},
+			"config.yaml":   &fstest.MapFile{Data: []byte("hosts: [a, b]\nlabels:\n  team: core\n")},
+		}
+
+		var cfg struct {
+			AllowedAddrs []string
+			Hosts        []string
+			Labels       map[string]string
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			SkipFlags: true,
+			SkipEnv:   true,
+			Sources: []cfgx.Source{
+				&cfgx.FileContentSource{PriorityLevel: cfgx.PrioritySecrets, FS: fakeFS},
+				&cfgx.FileSource{PriorityLevel: cfgx.PriorityFile, Path: "config.yaml", FS: fakeFS},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []string{"10.0.0.1", "10.0.0.2"}; !slices.Equal(cfg.AllowedAddrs, want) {
+			t.Errorf("AllowedAddrs: wanted %v, got %v", want, cfg.AllowedAddrs)
+		}
+		if want := []string{"a", "b"}; !slices.Equal(cfg.Hosts, want) {
+			t.Errorf("Hosts: wanted %v, got %v", want, cfg.Hosts)
+		}
+		if want := map[string]string{"team": "core"}; !maps.Equal(cfg.Labels, want) {
+			t.Errorf("Labels: wanted %v, got %v", want, cfg.Labels)
+		}
+	})
+
+	t.Run("Widths", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			I8  int8    `default:"-8"`
+			I16 int16   `default:"16"`
+			I32 int32   `default:"32"`
+			U8  uint8   `default:"255"`
+			U16 uint16  `default:"16"`
+			U32 uint32  `default:"32"`
+			U64 uint64  `default:"64"`
+			F32 float32 `default:"1.5"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.I8 != -8 || cfg.I16 != 16 || cfg.I32 != 32 {
+			t.Errorf("ints: got %d %d %d", cfg.I8, cfg.I16, cfg.I32)
+		}
+		if cfg.U8 != 255 || cfg.U16 != 16 || cfg.U32 != 32 || cfg.U64 != 64 {
+			t.Errorf("uints: got %d %d %d %d", cfg.U8, cfg.U16, cfg.U32, cfg.U64)
+		}
+		if cfg.F32 != 1.5 {
+			t.Errorf("F32: wanted 1.5, got %f", cfg.F32)
+		}
+	})
+
+	t.Run("Width_Overflow", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			U8 uint8 `default:"256" optional:"true"`
+		}
+
+		cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+
+		if cfg.U8 != 0 {
+			t.Errorf("U8: wanted 0 on overflow, got %d", cfg.U8)
+		}
+	})
+}
+
+func TestPresence(t *testing.T) {
+	type config struct {
+		Debug   bool
+		Retries int `default:"3"`
+		Name    string
+	}
+
+	t.Run("ZeroOverrides", func(t *testing.T) {
+		os.Setenv("PRESENCE_DEBUG", "true")
+		os.Setenv("PRESENCE_NAME", "api")
+		cleanupEnv(t, "PRESENCE_DEBUG", "PRESENCE_NAME")
+
+		var cfg config
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix: "PRESENCE",
+			Args:      []string{"--debug=false", "--retries=0"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Debug {
+			t.Error("Debug: wanted false from the flag over the env")
+		}
+		if cfg.Retries != 0 {
+			t.Errorf("Retries: wanted 0 from the flag over the default, got %d", cfg.Retries)
+		}
+	})
+
+	t.Run("ZeroSatisfiesRequired", func(t *testing.T) {
+		var cfg struct {
+			Workers int
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: []string{"--workers=0"}, SkipEnv: true})
+		if err != nil {
+			t.Fatalf("expected an explicit zero to satisfy required, got %v", err)
+		}
+	})
+
+	t.Run("EmptyListOverrides", func(t *testing.T) {
+		for _, gnu := range []bool{false, true} {
+			for _, env := range []map[string]string{{}, {"TAGS": "c"}} {
+				var cfg struct {
+					Tags []string `default:"a,b"`
+				}
+				err := cfgx.Parse(&cfg, cfgx.Options{Env: env, Args: []string{"--tags="}, GNUFlags: gnu})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Tags == nil || len(cfg.Tags) != 0 {
+					t.Errorf("GNUFlags %t, env %v: wanted an empty list from the flag, got %v", gnu, env, cfg.Tags)
+				}
+			}
+		}
+	})
+
+	t.Run("EmptyStringRequired", func(t *testing.T) {
+		var cfg struct {
+			APIKey string `env:"API_KEY"`
+			Region string `default:""`
+			Note   string `default:"" optional:"true"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Env: map[string]string{"API_KEY": ""}, Args: []string{}})
+		for _, want := range []string{"'APIKey': is required and must not be empty", "'Region': is required and must not be empty"} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q, got %v", want, err)
+			}
+		}
+		if strings.Contains(err.Error(), "Note") {
+			t.Errorf("expected the optional field to be empty, got %v", err)
+		}
+	})
+
+	t.Run("StructSkipped", func(t *testing.T) {
+		cfg := config{Name: "prepopulated"}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: []string{"--debug", "--name=flag"}, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Name != "prepopulated" {
+			t.Errorf("Name: wanted the field skipped, got %s", cfg.Name)
+		}
+	})
+
+	t.Run("StructValues", func(t *testing.T) {
+		cfg := config{Debug: true, Retries: 5, Name: "prepopulated"}
+
+		var prov cfgx.Provenance
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:         []string{"--name=flag"},
+			SkipEnv:      true,
+			StructValues: true,
+			Provenance:   &prov,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !cfg.Debug {
+			t.Error("Debug: wanted true from the struct")
+		}
+		if cfg.Retries != 3 {
+			t.Errorf("Retries: wanted the default over the struct, got %d", cfg.Retries)
+		}
+		if cfg.Name != "flag" {
+			t.Errorf("Name: wanted the flag over the struct, got %s", cfg.Name)
+		}
+
+		if f, _ := prov.Field("Debug"); f.Source == nil || f.Source.Source != "struct" {
+			t.Errorf("Debug: wanted source struct, got %+v", f.Source)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package cfgx_test
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+// color is a custom enum decoded with a registered decoder.
+type color int
+
+const (
+	red color = iota + 1
+	green
+)
+
+func parseColor(s string) (color, error) {
+	switch strings.ToLower(s) {
+	case "red":
+		return red, nil
+	case "green":
+		return green, nil
+	}
+	return 0, fmt.Errorf("unknown color %q", s)
+}
+
+// mode implements flag.Value.
+type mode string
+
+func (m *mode) String() string { return string(*m) }
+func (m *mode) Set(s string) error {
+	if s != "fast" && s != "safe" {
+		return fmt.Errorf("invalid mode %q", s)
+	}
+	*m = mode(s)
+	return nil
+}
+
+func TestDecoders(t *testing.T) {
+	t.Parallel()
+
+	t.Run("TextUnmarshaler", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			IP      net.IP       `default:"10.0.0.1"`
+			Level   slog.Level   `default:"warn"`
+			Start   time.Time    `default:"2024-01-02T03:04:05Z"`
+			Prefix  netip.Prefix `default:"10.0.0.0/8"`
+			Trusted []netip.Addr `default:"10.0.0.1,10.0.0.2"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := net.ParseIP("10.0.0.1"); !cfg.IP.Equal(want) {
+			t.Errorf("IP: wanted %v, got %v", want, cfg.IP)
+		}
+		if want := slog.LevelWarn; cfg.Level != want {
+			t.Errorf("Level: wanted %v, got %v", want, cfg.Level)
+		}
+		if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !cfg.Start.Equal(want) {
+			t.Errorf("Start: wanted %v, got %v", want, cfg.Start)
+		}
+		if want := netip.MustParsePrefix("10.0.0.0/8"); cfg.Prefix != want {
+			t.Errorf("Prefix: wanted %v, got %v", want, cfg.Prefix)
+		}
+		want := []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")}
+		if !slices.Equal(cfg.Trusted, want) {
+			t.Errorf("Trusted: wanted %v, got %v", want, cfg.Trusted)
+		}
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			API     *url.URL
+			Webhook url.URL `default:"https://hooks.example.com/x"`
+		}
+
+		args := []string{"--api", "https://api.example.com/v1"}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: args, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.API == nil || cfg.API.Host != "api.example.com" {
+			t.Errorf("API: wanted host api.example.com, got %v", cfg.API)
+		}
+		if want := "hooks.example.com"; cfg.Webhook.Host != want {
+			t.Errorf("Webhook: wanted host %s, got %s", want, cfg.Webhook.Host)
+		}
+	})
+
+	t.Run("FlagValue", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Mode mode `default:"safe"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: []string{"--mode=fast"}, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := mode("fast"); cfg.Mode != want {
+			t.Errorf("Mode: wanted %s, got %s", want, cfg.Mode)
+		}
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Color   color   `default:"green"`
+			Palette []color `default:"red,green"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			SkipFlags: true,
+			SkipEnv:   true,
+			Decoders: map[reflect.Type]cfgx.DecodeFunc{
+				reflect.TypeFor[color](): func(s string) (any, error) { return parseColor(s) },
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if cfg.Color != green {
+			t.Errorf("Color: wanted %v, got %v", green, cfg.Color)
+		}
+		if want := []color{red, green}; !slices.Equal(cfg.Palette, want) {
+			t.Errorf("Palette: wanted %v, got %v", want, cfg.Palette)
+		}
+	})
+
+	t.Run("CustomSource", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Timeout time.Duration
+		}
+
+		src := sourceFunc(func(fields map[string]cfgx.ConfigField) error {
+			return fields["Timeout"].Set("3s")
+		})
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{src}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := 3 * time.Second; cfg.Timeout != want {
+			t.Errorf("Timeout: wanted %v, got %v", want, cfg.Timeout)
+		}
+	})
+}
+
+// sourceFunc adapts a function to a cfgx.Source with priority 60.
+type sourceFunc func(map[string]cfgx.ConfigField) error
+
+func (f sourceFunc) Priority() int                                    { return 60 }
+func (f sourceFunc) Process(fields map[string]cfgx.ConfigField) error { return f(fields) }
@@ -0,0 +1,240 @@
+package cfgx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// k8sDataDir is the symlink Kubernetes swaps atomically to the
+// directory with the current files of a ConfigMap or Secret volume.
+const k8sDataDir = "..data"
+
+// ====================================================================
+// Directory trees
+
+// DirSource reads a directory tree with one file per field, such as a
+// Kubernetes ConfigMap or Secret volume. Nested struct paths map to
+// sub-directories or dotted file names, so DB.MaxConns is read from
+// "db/max_conns" or "db.max_conns". Names are matched like keys in a
+// [FileSource]: case-insensitively and ignoring "_" and "-".
+// Override the dotted path with the tag "key".
+//
+// A directory with a "..data" symlink is read through it, resolved once,
+// so all its files come from the same update. Hidden files are skipped.
+// Values are trimmed of surrounding whitespace.
+type DirSource struct {
+	PriorityLevel int
+	// Path is the root directory. A missing directory is skipped.
+	Path string
+	// FS is the file system to read instead of Path if set.
+	FS fs.FS
+}
+
+// NewDirSource sets a priority of PrioritySecrets (75) and reads
+// the directory at path from the OS.
+func NewDirSource(path string) *DirSource {
+	return &DirSource{
+		PriorityLevel: PrioritySecrets,
+		Path:          path,
+	}
+}
+
+// Priority implements [Source].
+func (s *DirSource) Priority() int {
+	return s.PriorityLevel
+}
+
+// Name implements [NamedSource].
+func (s *DirSource) Name() string {
+	return "dir"
+}
+
+// Process implements [Source].
+func (s *DirSource) Process(structMap map[string]ConfigField) error {
+	fsys, closeFS, err := s.open()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open directory %s: %w", s.Path, err)
+	}
+	defer closeFS()
+
+	files, err := s.files(fsys)
+	if err != nil {
+		return fmt.Errorf("read directory %s: %w", s.Path, err)
+	}
+
+	var allErrs []error
+
+	for name, field := range structMap {
+		key := name
+
+		// Override the key
+		if tagVal, ok := field.Tag.Lookup(tagKey); ok {
+			key = tagVal
+		}
+
+		file, ok := files[normalizeKeyPath(key)]
+		if !ok {
+			continue
+		}
+
+		val, err := readLimited(fsys, file)
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+
+		if err := field.Set(val); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return &MultiError{allErrs}
+	}
+	return nil
+}
+
+// Fingerprint implements [Fingerprinter] with a hash of the files.
+// A Kubernetes update changes the resolved names, so it is detected
+// even if the contents are the same.
+func (s *DirSource) Fingerprint() (string, error) {
+	fsys, closeFS, err := s.open()
+	if errors.Is(err, fs.ErrNotExist) {
+		return "missing", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer closeFS()
+
+	files, err := s.files(fsys)
+	if err != nil {
+		return "", err
+	}
+
+	return fingerprintFiles(fsys, slices.Collect(maps.Values(files)))
+}
+
+// open returns FS, or an [os.Root.FS] for Path.
+func (s *DirSource) open() (fs.FS, func(), error) {
+	if s.FS != nil {
+		return s.FS, func() {}, nil
+	}
+
+	root, err := os.OpenRoot(s.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return root.FS(), func() { root.Close() }, nil
+}
+
+// files maps the normalized key path of each file to its name in fsys.
+func (s *DirSource) files(fsys fs.FS) (map[string]string, error) {
+	files := map[string]string{}
+	if err := s.walk(fsys, ".", "", files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (s *DirSource) walk(fsys fs.FS, dir, key string, files map[string]string) error {
+	// Read a Kubernetes volume from the directory ..data points to. The
+	// other entries are symlinks into it, except volumes mounted below.
+	volume := false
+	if _, err := fs.Stat(fsys, path.Join(dir, k8sDataDir)); err == nil {
+		volume = true
+		if err := s.walk(fsys, s.resolve(path.Join(dir, k8sDataDir)), key, files); err != nil {
+			return err
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || volume && !entry.IsDir() {
+			continue
+		}
+
+		// Stat to follow symlinks
+		full := path.Join(dir, name)
+		info, err := fs.Stat(fsys, full)
+		if err != nil {
+			continue
+		}
+
+		nested := name
+		if key != "" {
+			nested = key + "." + name
+		}
+
+		if info.IsDir() {
+			if err := s.walk(fsys, full, nested, files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[normalizeKeyPath(nested)] = full
+	}
+
+	return nil
+}
+
+// resolve returns the target of the ..data symlink so the files are read
+// from one update even if it is swapped meanwhile. Only links on the
+// OS within Path are resolved; the link itself is used otherwise.
+func (s *DirSource) resolve(link string) string {
+	if s.FS != nil {
+		return link
+	}
+
+	target, err := os.Readlink(filepath.Join(s.Path, filepath.FromSlash(link)))
+	if err != nil {
+		return link
+	}
+
+	resolved := path.Join(path.Dir(link), filepath.ToSlash(target))
+	if !filepath.IsLocal(resolved) {
+		return link
+	}
+	return resolved
+}
+
+// readLimited reads a file of up to maxSecretSize and trims it.
+func readLimited(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", fmt.Errorf("cannot read file %s: %w", name, err)
+	}
+	defer file.Close()
+
+	return readTrimmed(file, name)
+}
+
+// readTrimmed reads up to maxSecretSize and trims the surrounding whitespace.
+func readTrimmed(r io.Reader, name string) (string, error) {
+	// Limit read size to prevent memory exhaustion
+	b, err := io.ReadAll(io.LimitReader(r, maxSecretSize+1))
+	if err != nil {
+		return "", fmt.Errorf("cannot read file %s: %w", name, err)
+	}
+	if len(b) > maxSecretSize {
+		return "", fmt.Errorf("file %s exceeds max size of %d bytes", name, maxSecretSize)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,157 @@
+package cfgx_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type dirConfig struct {
+	Port int
+	DB   struct {
+		Host     string
+		MaxConns int
+	}
+	Token    string `secret:"true"`
+	PoolSize int    `key:"database.pool_size" optional:"true"`
+}
+
+func TestDirSource(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"port":               {Data: []byte("8080\n")},
+		"db/host":            {Data: []byte("localhost")},
+		"db.max-conns":       {Data: []byte("10")},
+		"TOKEN":              {Data: []byte("s3cret\n")},
+		"database/pool_size": {Data: []byte("4")},
+		".hidden":            {Data: []byte("ignored")},
+	}
+
+	var cfg dirConfig
+	src := cfgx.NewDirSource("")
+	src.FS = fsys
+
+	var prov cfgx.Provenance
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		SkipFlags:  true,
+		SkipEnv:    true,
+		Sources:    []cfgx.Source{src},
+		Provenance: &prov,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 8080 || cfg.DB.Host != "localhost" || cfg.DB.MaxConns != 10 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cfg.Token != "s3cret" {
+		t.Errorf("Token: wanted s3cret, got %q", cfg.Token)
+	}
+	if cfg.PoolSize != 4 {
+		t.Errorf("PoolSize: wanted 4, got %d", cfg.PoolSize)
+	}
+	if f, _ := prov.Field("DB.Host"); f.Source == nil || f.Source.Source != "dir" {
+		t.Errorf("DB.Host: wanted source dir, got %+v", f.Source)
+	}
+}
+
+// writeK8sVolume writes the files the way the kubelet does: into a
+// timestamped directory, with ..data pointing to it and a symlink
+// for each key through ..data.
+func writeK8sVolume(t *testing.T, dir, version string, files map[string]string) {
+	t.Helper()
+
+	data := filepath.Join(dir, "..data")
+	versioned := filepath.Join(dir, "..2026_10_16_"+version)
+	if err := os.MkdirAll(versioned, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, val := range files {
+		if err := os.WriteFile(filepath.Join(versioned, name), []byte(val), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			if err := os.Symlink(filepath.Join("..data", name), link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Swap ..data atomically with a rename
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(filepath.Base(versioned), tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirSource_Kubernetes(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeK8sVolume(t, root, "1", map[string]string{"port": "8080", "token": "one"})
+
+	// A second volume mounted at a sub-directory
+	db := filepath.Join(root, "db")
+	if err := os.Mkdir(db, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeK8sVolume(t, db, "1", map[string]string{"host": "localhost", "max_conns": "10"})
+
+	src := cfgx.NewDirSource(root)
+	parse := func() dirConfig {
+		t.Helper()
+		var cfg dirConfig
+		if err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{src}}); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	cfg := parse()
+	if cfg.Port != 8080 || cfg.Token != "one" || cfg.DB.Host != "localhost" || cfg.DB.MaxConns != 10 {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	before, err := src.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An update swaps ..data to a new directory
+	writeK8sVolume(t, root, "2", map[string]string{"port": "9090", "token": "two"})
+
+	after, err := src.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Error("expected the fingerprint to change after an update")
+	}
+
+	cfg = parse()
+	if cfg.Port != 9090 || cfg.Token != "two" {
+		t.Errorf("expected the updated values, got %+v", cfg)
+	}
+}
+
+func TestDirSource_Missing(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		Port int `default:"8080"`
+	}
+	src := cfgx.NewDirSource(filepath.Join(t.TempDir(), "missing"))
+
+	if err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{src}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,401 @@
+package cfgx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"strings"
+)
+
+// PriorityDotEnv is just below environment variables, so the
+// real environment overrides .env files.
+const PriorityDotEnv = 40
+
+// ====================================================================
+// .env files
+
+// DotEnvSource reads .env files and maps the variables onto the fields
+// with the same names as environment variables, e.g. APP_DB_HOST for
+// DB.Host with the prefix APP, or the "env" tag.
+//
+// Each line is KEY=value, optionally prefixed with "export". Lines
+// starting with # are comments. Values can be single quoted (literal),
+// double quoted (with \n, \t, \" and \\ escapes), or unquoted with a
+// trailing # comment. Quoted values can span multiple lines.
+// ${VAR}, $VAR and ${VAR:-default} are expanded in double quoted and
+// unquoted values, from the environment or earlier variables. Other
+// references, such as ${DB.User}, and $${ are kept for [Options.Resolve].
+type DotEnvSource struct {
+	PriorityLevel int
+	// Paths are the files to read in order, so later files override
+	// earlier ones. Missing files are skipped.
+	Paths []string
+	// FS is the file system to read Paths from. If nil, they are read with [os.ReadFile].
+	FS fs.FS
+	// EnvPrefix is added to the variable names (defaults to [Options.EnvPrefix]).
+	EnvPrefix string
+
+	env map[string]string // [Options.Env] for expanding variables
+}
+
+// NewDotEnvSource sets a priority of PriorityDotEnv (40) and reads the
+// files at paths from the OS, defaulting to ".env" and then ".env.local".
+func NewDotEnvSource(paths ...string) *DotEnvSource {
+	if len(paths) == 0 {
+		paths = []string{".env", ".env.local"}
+	}
+	return &DotEnvSource{
+		PriorityLevel: PriorityDotEnv,
+		Paths:         paths,
+	}
+}
+
+// Priority implements [Source].
+func (s *DotEnvSource) Priority() int {
+	return s.PriorityLevel
+}
+
+// Name implements [NamedSource].
+func (s *DotEnvSource) Name() string {
+	return "dotenv"
+}
+
+// Process implements [Source].
+func (s *DotEnvSource) Process(structMap map[string]ConfigField) error {
+	vars, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	var allErrs []error
+
+	toName := func(path string) string { return envPathName(path, s.EnvPrefix) }
+	lookup := func(name string) (string, bool) {
+		val, ok := vars[name]
+		return val, ok
+	}
+
+	for _, field := range structMap {
+		val, ok, err := field.lookupNames(nameEnv, envName(field, s.EnvPrefix), toName, lookup)
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err := field.Set(val); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return &MultiError{allErrs}
+	}
+	return nil
+}
+
+// Fingerprint implements [Fingerprinter] with a hash of the files.
+func (s *DotEnvSource) Fingerprint() (string, error) {
+	h := sha256.New()
+
+	for _, path := range s.Paths {
+		b, err := s.read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(h, "%s\x00missing\x00", path)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(b))
+		h.Write(b)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// load reads and merges the files in order.
+func (s *DotEnvSource) load() (map[string]string, error) {
+	vars := map[string]string{}
+
+	for _, path := range s.Paths {
+		b, err := s.read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read env file %s: %w", path, err)
+		}
+
+		fileVars, err := parseDotEnv(string(b), vars, lookupEnv(s.env))
+		if err != nil {
+			return nil, fmt.Errorf("parse env file %s: %w", path, err)
+		}
+		maps.Copy(vars, fileVars)
+	}
+
+	return vars, nil
+}
+
+func (s *DotEnvSource) read(path string) ([]byte, error) {
+	if s.FS != nil {
+		return fs.ReadFile(s.FS, path)
+	}
+	return os.ReadFile(path)
+}
+
+// parseDotEnv parses the contents of a .env file. Variables are
+// expanded from the environment, then the file, then prev.
+func parseDotEnv(data string, prev map[string]string, env func(string) (string, bool)) (map[string]string, error) {
+	p := &dotEnvParser{
+		data: strings.ReplaceAll(data, "\r\n", "\n"),
+		line: 1,
+		prev: prev,
+		vars: map[string]string{},
+		env:  env,
+	}
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			return p.vars, nil
+		}
+
+		// Comments and blank lines
+		if p.peek() == '#' || p.peek() == '\n' {
+			p.skipLine()
+			continue
+		}
+
+		line := p.line
+		key := p.readKey()
+		p.skipSpace()
+		if key == "export" && !p.done() && p.peek() != '=' {
+			key = p.readKey()
+			p.skipSpace()
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: expected a variable name", line)
+		}
+
+		if p.done() || p.peek() != '=' {
+			return nil, fmt.Errorf("line %d: expected = after %s", line, key)
+		}
+		p.pos++
+		p.skipSpace()
+
+		val, err := p.readValue()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", line, key, err)
+		}
+		p.vars[key] = val
+	}
+}
+
+type dotEnvParser struct {
+	data string
+	pos  int
+	line int
+	prev map[string]string
+	vars map[string]string
+	env  func(string) (string, bool)
+}
+
+func (p *dotEnvParser) done() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *dotEnvParser) peek() byte {
+	return p.data[p.pos]
+}
+
+// next returns the next byte and counts lines.
+func (p *dotEnvParser) next() byte {
+	c := p.data[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipSpace skips spaces and tabs but not newlines.
+func (p *dotEnvParser) skipSpace() {
+	for !p.done() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *dotEnvParser) skipLine() {
+	for !p.done() && p.next() != '\n' {
+	}
+}
+
+func (p *dotEnvParser) readKey() string {
+	start := p.pos
+	for !p.done() && isEnvNameChar(p.peek()) {
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+// readValue reads a quoted or unquoted value and the rest of its line.
+func (p *dotEnvParser) readValue() (string, error) {
+	if p.done() {
+		return "", nil
+	}
+
+	var val string
+	switch p.peek() {
+	case '\'':
+		p.pos++
+		start := p.pos
+		for !p.done() && p.peek() != '\'' {
+			p.next()
+		}
+		if p.done() {
+			return "", errors.New("unterminated single quote")
+		}
+		val = p.data[start:p.pos]
+		p.pos++
+
+	case '"':
+		p.pos++
+		var b strings.Builder
+		for !p.done() && p.peek() != '"' {
+			c := p.next()
+			switch {
+			case c == '\\' && !p.done():
+				b.WriteString(unescape(p.next()))
+			case c == '$':
+				b.WriteString(p.expand())
+			default:
+				b.WriteByte(c)
+			}
+		}
+		if p.done() {
+			return "", errors.New("unterminated double quote")
+		}
+		val = b.String()
+		p.pos++
+
+	default:
+		var b strings.Builder
+		for !p.done() && p.peek() != '\n' {
+			// An inline comment must follow whitespace
+			if p.peek() == '#' && (p.data[p.pos-1] == ' ' || p.data[p.pos-1] == '\t') {
+				break
+			}
+			if c := p.next(); c == '$' {
+				b.WriteString(p.expand())
+			} else {
+				b.WriteByte(c)
+			}
+		}
+		return strings.TrimSpace(b.String()), p.endLine()
+	}
+
+	return val, p.endLine()
+}
+
+// endLine allows only whitespace and a comment after a quoted value.
+func (p *dotEnvParser) endLine() error {
+	p.skipSpace()
+	if p.done() {
+		return nil
+	}
+	switch p.peek() {
+	case '\n':
+		p.next()
+		return nil
+	case '#':
+		p.skipLine()
+		return nil
+	default:
+		return fmt.Errorf("unexpected %q after value", p.peek())
+	}
+}
+
+// expand reads a variable reference after a $ and returns its value,
+// or the reference as is if there is no such variable.
+func (p *dotEnvParser) expand() string {
+	if p.done() {
+		return "$"
+	}
+
+	// Keep the escape in $${
+	if p.peek() == '$' {
+		p.next()
+		return "$$"
+	}
+
+	if p.peek() != '{' {
+		name := p.readKey()
+		if name == "" {
+			return "$"
+		}
+		if val, ok := p.lookup(name); ok {
+			return val
+		}
+		return "$" + name
+	}
+
+	// The } must be on the same line and before a closing quote,
+	// or the ${ is kept as is
+	rest := p.data[p.pos:]
+	if i := strings.IndexAny(rest, "\n\""); i >= 0 {
+		rest = rest[:i]
+	}
+	end := strings.IndexByte(rest, '}')
+	if end < 0 {
+		return "$"
+	}
+	ref := p.data[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	name, fallback, hasFallback := strings.Cut(ref, ":-")
+	val, ok := p.lookup(name)
+	switch {
+	case hasFallback && (!ok || val == ""):
+		return fallback
+	case !ok:
+		return "${" + ref + "}"
+	}
+	return val
+}
+
+func (p *dotEnvParser) lookup(name string) (string, bool) {
+	if val, ok := p.env(name); ok {
+		return val, true
+	}
+	if val, ok := p.vars[name]; ok {
+		return val, true
+	}
+	val, ok := p.prev[name]
+	return val, ok
+}
+
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '"', '\\', '$':
+		return string(c)
+	default:
+		return `\` + string(c)
+	}
+}
+
+func isEnvNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,245 @@
+package cfgx_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+func TestDotEnvSource(t *testing.T) {
+	os.Setenv("DOTENV_TEST_HOME", "/home/app")
+	cleanupEnv(t, "DOTENV_TEST_HOME")
+
+	fsys := fstest.MapFS{
+		".env": {Data: []byte(`# Database
+export APP_DB_HOST=localhost
+APP_DB_PORT = 5432 # inline comment
+APP_NAME='literal $NOT_EXPANDED # not a comment'
+APP_GREETING="hello\tworld\n\"quoted\""
+APP_CERT="-----BEGIN-----
+line two
+-----END-----"
+APP_DATA_DIR=${DOTENV_TEST_HOME}/data
+APP_URL=postgres://$APP_DB_HOST:${APP_DB_PORT}/db
+APP_LEVEL=${APP_UNSET:-info}
+APP_TAGS=a,b#c
+`)},
+		".env.local": {Data: []byte("APP_DB_HOST=override\r\nAPP_CACHE_DIR=${APP_DATA_DIR}/cache\r\n")},
+	}
+
+	var cfg struct {
+		DB struct {
+			Host string
+			Port int
+		}
+		Name     string
+		Greeting string
+		Cert     string
+		DataDir  string
+		CacheDir string
+		URL      string
+		Level    string
+		Tags     []string
+	}
+
+	src := cfgx.NewDotEnvSource()
+	src.FS = fsys
+
+	var prov cfgx.Provenance
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		EnvPrefix:  "APP",
+		SkipFlags:  true,
+		Sources:    []cfgx.Source{src},
+		Provenance: &prov,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DB.Host":  "override",
+		"Name":     "literal $NOT_EXPANDED # not a comment",
+		"Greeting": "hello\tworld\n\"quoted\"",
+		"Cert":     "-----BEGIN-----\nline two\n-----END-----",
+		"DataDir":  "/home/app/data",
+		"CacheDir": "/home/app/data/cache",
+		"URL":      "postgres://localhost:5432/db",
+		"Level":    "info",
+	}
+	got := map[string]string{
+		"DB.Host":  cfg.DB.Host,
+		"Name":     cfg.Name,
+		"Greeting": cfg.Greeting,
+		"Cert":     cfg.Cert,
+		"DataDir":  cfg.DataDir,
+		"CacheDir": cfg.CacheDir,
+		"URL":      cfg.URL,
+		"Level":    cfg.Level,
+	}
+	for path, w := range want {
+		if got[path] != w {
+			t.Errorf("%s: wanted %q, got %q", path, w, got[path])
+		}
+	}
+	if cfg.DB.Port != 5432 {
+		t.Errorf("DB.Port: wanted 5432, got %d", cfg.DB.Port)
+	}
+	if strings.Join(cfg.Tags, "|") != "a|b#c" {
+		t.Errorf("Tags: wanted [a b#c], got %v", cfg.Tags)
+	}
+
+	if f, _ := prov.Field("DB.Host"); f.Source == nil || f.Source.Source != "dotenv" {
+		t.Errorf("DB.Host: wanted source dotenv, got %+v", f.Source)
+	}
+}
+
+func TestDotEnvSource_EnvOverrides(t *testing.T) {
+	os.Setenv("DOTENV_PORT", "9000")
+	cleanupEnv(t, "DOTENV_PORT")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+	if err := os.WriteFile(path, []byte("DOTENV_PORT=8080\nDOTENV_HOST=localhost\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg struct {
+		Port int
+		Host string
+	}
+
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		EnvPrefix: "DOTENV",
+		SkipFlags: true,
+		Sources:   []cfgx.Source{cfgx.NewDotEnvSource(path, filepath.Join(dir, ".env.missing"))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9000 {
+		t.Errorf("Port: wanted the environment over the file, got %d", cfg.Port)
+	}
+	if cfg.Host != "localhost" {
+		t.Errorf("Host: wanted localhost, got %s", cfg.Host)
+	}
+}
+
+func TestDotEnvSource_UnclosedReference(t *testing.T) {
+	t.Parallel()
+
+	// An unclosed ${ is kept and doesn't swallow the next lines
+	fsys := fstest.MapFS{".env": {Data: []byte("A=x${FOO\nB={y}\nPORT=1\nQ=\"q${FOO\" # }\n")}}
+
+	var cfg struct {
+		A    string
+		B    string
+		Port int
+		Q    string
+	}
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		Env:     map[string]string{},
+		Args:    []string{},
+		Sources: []cfgx.Source{&cfgx.DotEnvSource{PriorityLevel: cfgx.PriorityDotEnv, Paths: []string{".env"}, FS: fsys}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.A != "x${FOO" || cfg.B != "{y}" || cfg.Port != 1 || cfg.Q != "q${FOO" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestDotEnvSource_References(t *testing.T) {
+	t.Parallel()
+
+	// The same values from the environment and a .env file
+	vars := map[string]string{
+		"USER":    "bob",
+		"URL":     "pg://${User}@h",
+		"PATTERN": "$${literal}",
+		"TYPO":    "${UNDEFINED_VAR}",
+	}
+	var data strings.Builder
+	for _, name := range []string{"USER", "URL", "PATTERN", "TYPO"} {
+		data.WriteString(name + "=" + vars[name] + "\n")
+	}
+
+	type config struct {
+		User    string
+		URL     string
+		Pattern string
+		Typo    string `optional:"true"`
+	}
+
+	parse := func(opts cfgx.Options) (config, error) {
+		var cfg config
+		opts.Args = []string{}
+		err := cfgx.Parse(&cfg, opts)
+		return cfg, err
+	}
+	dotenv := cfgx.Options{
+		Env: map[string]string{},
+		Sources: []cfgx.Source{&cfgx.DotEnvSource{
+			PriorityLevel: cfgx.PriorityDotEnv,
+			Paths:         []string{".env"},
+			FS:            fstest.MapFS{".env": {Data: []byte(data.String())}},
+		}},
+	}
+	env := cfgx.Options{Env: vars}
+
+	// Kept as is without Resolve
+	fromEnv, envErr := parse(env)
+	fromFile, fileErr := parse(dotenv)
+	if envErr != nil || fileErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", envErr, fileErr)
+	}
+	if fromFile != fromEnv || fromFile.URL != "pg://${User}@h" || fromFile.Typo != "${UNDEFINED_VAR}" {
+		t.Errorf("wanted %+v, got %+v", fromEnv, fromFile)
+	}
+
+	// Resolved the same way, including the error for the typo
+	env.Resolve, dotenv.Resolve = true, true
+	fromEnv, envErr = parse(env)
+	fromFile, fileErr = parse(dotenv)
+	if fromFile.URL != "pg://bob@h" || fromFile.Pattern != "${literal}" || fromFile.URL != fromEnv.URL || fromFile.Pattern != fromEnv.Pattern {
+		t.Errorf("wanted %+v, got %+v", fromEnv, fromFile)
+	}
+	for _, err := range []error{envErr, fileErr} {
+		if err == nil || !strings.Contains(err.Error(), "${UNDEFINED_VAR} is not a field or environment variable") {
+			t.Errorf("expected an error for the typo, got %v", err)
+		}
+	}
+}
+
+func TestDotEnvSource_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"NoEquals":          "APP_PORT 8080\n",
+		"UnterminatedQuote": "APP_NAME=\"open\n",
+		"TrailingText":      "APP_NAME='a' b\n",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			src := cfgx.NewDotEnvSource(".env")
+			src.FS = fstest.MapFS{".env": {Data: []byte(data)}}
+
+			var cfg struct {
+				Port int    `optional:"true"`
+				Name string `optional:"true"`
+			}
+			err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{src}})
+			if err == nil || !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("expected an error for line 1, got %v", err)
+			}
+		})
+	}
+}
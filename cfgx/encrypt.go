@@ -0,0 +1,55 @@
+package cfgx
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// encPrefix is the scheme of encrypted values.
+const encPrefix = "enc"
+
+// Decrypter decrypts values written as "enc:<base64>". A kv.Encryptor,
+// such as kv.AESEncryptor, satisfies it.
+type Decrypter interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// Encrypter encrypts values for [EncryptValue]. A kv.Encryptor satisfies it.
+type Encrypter interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+}
+
+// EncryptValue encrypts the plaintext and returns it as "enc:<base64>",
+// the format decrypted by [Options.Decrypter].
+func EncryptValue(ctx context.Context, e Encrypter, plaintext []byte) (string, error) {
+	ciphertext, err := e.Encrypt(ctx, plaintext)
+	if err != nil {
+		return "", fmt.Errorf("encrypt value: %w", err)
+	}
+	return encPrefix + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptResolver returns a [ResolveFunc] that decrypts base64 encoded
+// ciphertext. [Options.Decrypter] registers it for the "enc" scheme.
+func DecryptResolver(d Decrypter) ResolveFunc {
+	return func(ref string) (string, error) {
+		ciphertext, err := base64.StdEncoding.DecodeString(ref)
+		if err != nil {
+			return "", fmt.Errorf("decode encrypted value: %w", err)
+		}
+
+		plaintext, err := d.Decrypt(context.Background(), ciphertext)
+		if err != nil {
+			return "", fmt.Errorf("decrypt value: %w", err)
+		}
+		return string(plaintext), nil
+	}
+}
+
+// noDecrypter rejects encrypted values when [Options.Decrypter] is not
+// set, so they are never used as the plain value.
+func noDecrypter(string) (string, error) {
+	return "", errors.New("encrypted value but no Decrypter configured")
+}
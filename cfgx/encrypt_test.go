@@ -0,0 +1,90 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+	"github.com/erlorenz/go-toolbox/kv"
+)
+
+func TestDecrypter(t *testing.T) {
+	enc, err := kv.NewAESEncryptor(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password, err := cfgx.EncryptValue(context.Background(), enc, []byte("s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(password, "enc:") {
+		t.Fatalf("expected the enc: prefix, got %s", password)
+	}
+
+	os.Setenv("ENC_DB_PASSWORD", password)
+	cleanupEnv(t, "ENC_DB_PASSWORD")
+
+	t.Run("Decrypt", func(t *testing.T) {
+		var cfg struct {
+			DB struct {
+				Password string `secret:"true"`
+			}
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "ENC", SkipFlags: true, Decrypter: enc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DB.Password != "s3cret" {
+			t.Errorf("DB.Password: wanted s3cret, got %q", cfg.DB.Password)
+		}
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		other, err := kv.NewAESEncryptor(bytes.Repeat([]byte{8}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cfg struct {
+			DB struct {
+				Password string `secret:"true"`
+			}
+		}
+		err = cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "ENC", SkipFlags: true, Decrypter: other})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "env" || srcErr.Field != "DB.Password" {
+			t.Fatalf("expected env error for DB.Password, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "decrypt value") {
+			t.Errorf("expected a decrypt error, got %v", err)
+		}
+	})
+
+	t.Run("NoDecrypter", func(t *testing.T) {
+		var cfg struct {
+			DB struct {
+				Password string
+			}
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "ENC", SkipFlags: true})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "env" || srcErr.Field != "DB.Password" {
+			t.Fatalf("expected env error for DB.Password, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "encrypted value but no Decrypter configured") {
+			t.Errorf("unexpected message: %v", err)
+		}
+		if cfg.DB.Password == password {
+			t.Errorf("DB.Password: the encrypted value was used as is")
+		}
+	})
+}
@@ -1,7 +1,9 @@
 package cfgx
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -36,3 +38,80 @@ func (m *MultiError) Error() string {
 	return fmt.Sprintf("%d error(s) occurred parsing config:\n- %s",
 		len(m.Errors), strings.Join(errMsgs, "\n- "))
 }
+
+// Unwrap returns the errors so [errors.Is] and [errors.As]
+// can match a [ValidationError].
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+// SourceError is an error returned by a source, annotated with the
+// name of the source and the path of the field if it is about one.
+type SourceError struct {
+	Source string `json:"source"`
+	Field  string `json:"field,omitempty"`
+	Err    error  `json:"-"`
+}
+
+// Error implements the error interface.
+func (e *SourceError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s: cannot set %s: %v", e.Source, e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError is returned by [ConfigField.Set] so the path can be
+// added to the [SourceError].
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("cannot set %s: %v", e.path, e.err)
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// sourceErrors flattens the error returned by a source into a
+// [SourceError] for each underlying error.
+func sourceErrors(source Source, err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	if multi, ok := err.(*MultiError); ok {
+		var errs []error
+		for _, err := range multi.Errors {
+			errs = append(errs, sourceErrors(source, err)...)
+		}
+		return errs
+	}
+
+	srcErr := &SourceError{Source: sourceName(source), Err: err}
+
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		srcErr.Field = fieldErr.path
+		srcErr.Err = fieldErr.err
+	}
+
+	return []error{srcErr}
+}
+
+// hasFieldError reports whether one of the errors is a [SourceError]
+// for the field.
+func hasFieldError(errs []error, path string) bool {
+	return slices.ContainsFunc(errs, func(err error) bool {
+		srcErr, ok := err.(*SourceError)
+		return ok && srcErr.Field == path
+	})
+}
@@ -0,0 +1,243 @@
+package cfgx_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+func TestSourceErrors(t *testing.T) {
+	type config struct {
+		Port    int           `default:"8080"`
+		Timeout time.Duration `default:"5s"`
+		Token   string        `secret:"true" optional:"true"`
+		Host    string
+	}
+
+	t.Run("Env", func(t *testing.T) {
+		os.Setenv("SRCERR_PORT", "abc")
+		os.Setenv("SRCERR_HOST", "localhost")
+		cleanupEnv(t, "SRCERR_PORT", "SRCERR_HOST")
+
+		var cfg config
+		err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "SRCERR", SkipFlags: true})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) {
+			t.Fatalf("expected SourceError, got %v", err)
+		}
+		if srcErr.Source != "env" || srcErr.Field != "Port" {
+			t.Errorf("expected env and Port, got %s and %s", srcErr.Source, srcErr.Field)
+		}
+		if !strings.HasPrefix(srcErr.Error(), "env: cannot set Port: ") {
+			t.Errorf("unexpected message: %s", srcErr.Error())
+		}
+	})
+
+	t.Run("NotAlsoRequired", func(t *testing.T) {
+		type required struct {
+			Port int
+		}
+		var cfg required
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:    []string{"--port", "abc"},
+			SkipEnv: true,
+			Output:  io.Discard,
+		})
+
+		var multi *cfgx.MultiError
+		if !errors.As(err, &multi) {
+			t.Fatalf("expected MultiError, got %v", err)
+		}
+		if len(multi.Errors) != 1 {
+			t.Fatalf("expected 1 error, got %v", multi.Errors)
+		}
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "flag" || srcErr.Field != "Port" {
+			t.Errorf("expected flag error for Port, got %v", err)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		type badDefault struct {
+			Timeout time.Duration `default:"5 seconds"`
+		}
+		var cfg badDefault
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "default" || srcErr.Field != "Timeout" {
+			t.Errorf("expected default error for Timeout, got %v", err)
+		}
+	})
+
+	t.Run("SecretRedacted", func(t *testing.T) {
+		type secret struct {
+			Token int `secret:"true"`
+		}
+		var cfg secret
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:    []string{"--token", "hunter2"},
+			SkipEnv: true,
+			Output:  io.Discard,
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("secret leaked in error: %v", err)
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		failing := sourceFunc(func(map[string]cfgx.ConfigField) error {
+			return errors.New("connection refused")
+		})
+
+		var cfg config
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			SkipFlags: true,
+			SkipEnv:   true,
+			Sources:   []cfgx.Source{failing},
+		})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Field != "" {
+			t.Fatalf("expected SourceError without a field, got %v", err)
+		}
+		if srcErr.Source != "cfgx_test.sourceFunc" {
+			t.Errorf("expected the type as the name, got %s", srcErr.Source)
+		}
+	})
+}
+
+func TestStrict(t *testing.T) {
+	type config struct {
+		Port int    `default:"8080"`
+		Host string `default:"localhost"`
+		TLS  bool   `optional:"true"`
+	}
+
+	t.Run("UnknownFlagsIgnored", func(t *testing.T) {
+		var cfg config
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:    []string{"--verbose", "--level=debug", "--tls", "--port", "9000", "-x=1", "--host=example.com"},
+			SkipEnv: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Port != 9000 || cfg.Host != "example.com" || !cfg.TLS {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("UnknownFlagBeforeArg", func(t *testing.T) {
+		for _, gnu := range []bool{false, true} {
+			var cfg struct {
+				Verbose bool     `optional:"true"`
+				Args    []string `args:"true"`
+			}
+			err := cfgx.Parse(&cfg, cfgx.Options{Args: []string{"--debug", "serve"}, SkipEnv: true, GNUFlags: gnu})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(cfg.Args, []string{"serve"}) {
+				t.Errorf("GNUFlags %t: expected the arg to be kept, got %v", gnu, cfg.Args)
+			}
+		}
+	})
+
+	t.Run("KnownAfterUnknownValue", func(t *testing.T) {
+		for _, gnu := range []bool{false, true} {
+			var cfg struct {
+				Port  int      `default:"3"`
+				Debug bool     `default:"true"`
+				Args  []string `args:"true" optional:"true"`
+			}
+			err := cfgx.Parse(&cfg, cfgx.Options{
+				Args:     []string{"--unknown", "foo", "--port", "0", "--debug=false", "--", "-x"},
+				SkipEnv:  true,
+				GNUFlags: gnu,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != 0 || cfg.Debug || !slices.Equal(cfg.Args, []string{"foo", "-x"}) {
+				t.Errorf("GNUFlags %t: expected the flags after the unknown one, got %+v", gnu, cfg)
+			}
+		}
+	})
+
+	t.Run("AlreadySet", func(t *testing.T) {
+		for _, gnu := range []bool{false, true} {
+			// Fields that are already set are skipped, but not unknown
+			cfg := config{Port: 9000}
+			err := cfgx.Parse(&cfg, cfgx.Options{
+				EnvPrefix: "STRICT",
+				Env:       map[string]string{"STRICT_PORT": "1"},
+				Args:      []string{"--port", "2", "--host", "example.com"},
+				GNUFlags:  gnu,
+				Strict:    true,
+				Output:    io.Discard,
+			})
+			if err != nil {
+				t.Fatalf("GNUFlags %t: %v", gnu, err)
+			}
+			if cfg.Port != 9000 || cfg.Host != "example.com" {
+				t.Errorf("GNUFlags %t: unexpected config: %+v", gnu, cfg)
+			}
+		}
+	})
+
+	t.Run("UnknownFlag", func(t *testing.T) {
+		var cfg config
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:    []string{"--prot", "9000"},
+			SkipEnv: true,
+			Strict:  true,
+			Output:  io.Discard,
+		})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "flag" {
+			t.Fatalf("expected flag error, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "prot") {
+			t.Errorf("expected the flag name in the error, got %v", err)
+		}
+	})
+
+	t.Run("UnknownEnv", func(t *testing.T) {
+		os.Setenv("STRICT_PORT", "9000")
+		os.Setenv("STRICT_HSOT", "example.com")
+		cleanupEnv(t, "STRICT_PORT", "STRICT_HSOT")
+
+		var cfg config
+		err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "STRICT", SkipFlags: true, Strict: true})
+
+		var srcErr *cfgx.SourceError
+		if !errors.As(err, &srcErr) || srcErr.Source != "env" {
+			t.Fatalf("expected env error, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "STRICT_HSOT") {
+			t.Errorf("expected the variable in the error, got %v", err)
+		}
+		if cfg.Port != 9000 {
+			t.Errorf("expected known variables to be set, got %d", cfg.Port)
+		}
+
+		// Not an error without strict
+		cfg = config{}
+		if err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "STRICT", SkipFlags: true}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,358 @@
+package cfgx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/erlorenz/go-toolbox/casing"
+	"go.yaml.in/yaml/v3"
+)
+
+// SecretMode sets how the export functions write fields tagged secret.
+type SecretMode int
+
+const (
+	SecretsRedact SecretMode = iota // Write [Redacted] unless the value is empty
+	SecretsOmit                     // Leave the field out
+	SecretsShow                     // Write the value
+)
+
+// ExportOptions holds options for [ExportJSON], [ExportYAML] and [ExportEnv].
+type ExportOptions struct {
+	// EnvPrefix is added to the variable names in [ExportEnv], as in [Options].
+	EnvPrefix string
+	// Decoders are the decoders passed to [Parse]. Struct types with
+	// a decoder are written as one value instead of nested fields.
+	Decoders map[reflect.Type]DecodeFunc
+	// Secrets sets how fields tagged secret are written (defaults to [SecretsRedact]).
+	Secrets SecretMode
+}
+
+// ExportJSON writes the config struct as indented JSON that a
+// [FileSource] reads back: nested structs are objects, keys are the
+// kebab-case field names or the "key" tag, and durations and types
+// with a MarshalText or String method are strings.
+//
+//	cfgx.ExportJSON(os.Stdout, &cfg, cfgx.ExportOptions{})
+func ExportJSON(w io.Writer, cfg any, opts ExportOptions) error {
+	root, err := exportTree(cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, root.children)
+}
+
+// ExportYAML writes the config struct as YAML with the same keys and
+// values as [ExportJSON], in declaration order.
+func ExportYAML(w io.Writer, cfg any, opts ExportOptions) error {
+	root, err := exportTree(cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	node, err := yamlMapping(root.children)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ExportEnv writes the config struct as KEY=value lines that the
+// environment or a [DotEnvSource] reads back. Names are the same as
+// for environment variables, and slices and maps are joined with the
+// separator. Values that are not plain words are double quoted, and
+// nil pointers are left out.
+//
+//	APP_PORT=8080
+//	APP_DB_HOSTS=a,b
+//	APP_GREETING="hello world"
+func ExportEnv(w io.Writer, cfg any, opts ExportOptions) error {
+	fields, err := exportFields(cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		// A nil pointer is left out, since an empty value would set it
+		if field.Kind == reflect.Pointer && field.Value.IsNil() {
+			continue
+		}
+
+		value := Redacted
+		if !field.isSecret() || opts.Secrets == SecretsShow || field.Value.IsZero() {
+			value = envValue(field)
+		}
+
+		if _, err := fmt.Fprintf(w, "%s=%s\n", envName(field, opts.EnvPrefix), quoteEnv(value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportFields walks the config struct, leaving out
+// the secret fields if they are omitted.
+func exportFields(cfg any, opts ExportOptions) ([]ConfigField, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotPointerToStruct
+	}
+
+	fields := orderedFields(walkStruct(v.Elem(), "", nil, opts.Decoders))
+	if opts.Secrets == SecretsOmit {
+		fields = slices.DeleteFunc(fields, ConfigField.isSecret)
+	}
+	return fields, nil
+}
+
+// exportNode is a key in the JSON and YAML output, with
+// either a value or the nested keys.
+type exportNode struct {
+	key      string
+	value    any
+	children []*exportNode
+	comment  string // Written above the key in YAML
+}
+
+// child returns the nested key, adding it if it does not exist.
+func (n *exportNode) child(key string) *exportNode {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	c := &exportNode{key: key}
+	n.children = append(n.children, c)
+	return c
+}
+
+// exportTree nests the fields by their dotted keys in declaration order.
+func exportTree(cfg any, opts ExportOptions) (*exportNode, error) {
+	fields, err := exportFields(cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &exportNode{}
+	for _, field := range fields {
+		node := root
+		for _, key := range exportKey(field) {
+			node = node.child(key)
+		}
+
+		if field.isSecret() && opts.Secrets != SecretsShow && !field.Value.IsZero() {
+			node.value = Redacted
+			continue
+		}
+		node.value = exportValue(field.Value)
+	}
+
+	return root, nil
+}
+
+// exportKey is the "key" tag or the kebab-case path, split on the dots.
+func exportKey(field ConfigField) []string {
+	if tagVal, ok := field.Tag.Lookup(tagKey); ok {
+		return strings.Split(tagVal, ".")
+	}
+
+	keys := strings.Split(field.Path, ".")
+	for i, key := range keys {
+		keys[i] = casing.ToKebab(key)
+	}
+	return keys
+}
+
+// exportValue converts the value to a bool, number or string that
+// parses back into it, or a list or map of those. A nil pointer is
+// nil, which is null in JSON and YAML and leaves the field unset.
+func exportValue(v reflect.Value) any {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	if s, ok := textOf(v); ok {
+		return s
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return exportValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		items := make([]any, v.Len())
+		for i := range v.Len() {
+			items[i] = exportValue(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		m := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m[exportString(iter.Key())] = exportValue(iter.Value())
+		}
+		return m
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface()
+	default:
+		return stringOf(v)
+	}
+}
+
+// exportString formats a single value as the string the field setters expect.
+func exportString(v reflect.Value) string {
+	if s, ok := textOf(v); ok {
+		return s
+	}
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		return exportString(v.Elem())
+	}
+	return stringOf(v)
+}
+
+// textOf formats values with a MarshalText or String method, such as durations.
+func textOf(v reflect.Value) (string, bool) {
+	candidates := []reflect.Value{v}
+	if v.CanAddr() {
+		candidates = append(candidates, v.Addr())
+	}
+
+	for _, c := range candidates {
+		if c.Kind() == reflect.Pointer && c.IsNil() {
+			continue
+		}
+		switch c := c.Interface().(type) {
+		case encoding.TextMarshaler:
+			if b, err := c.MarshalText(); err == nil {
+				return string(b), true
+			}
+		case fmt.Stringer:
+			return c.String(), true
+		}
+	}
+	return "", false
+}
+
+// envValue joins list items with the separator, and map entries as
+// key=value items sorted by key.
+func envValue(field ConfigField) string {
+	if field.isList() && field.Kind == reflect.Map {
+		items := make([]string, 0, field.Value.Len())
+		for iter := field.Value.MapRange(); iter.Next(); {
+			items = append(items, exportString(iter.Key())+"="+exportString(iter.Value()))
+		}
+		slices.Sort(items)
+		return strings.Join(items, field.separator())
+	}
+
+	if field.isList() {
+		items := make([]string, field.Value.Len())
+		for i := range field.Value.Len() {
+			items[i] = exportString(field.Value.Index(i))
+		}
+		return strings.Join(items, field.separator())
+	}
+
+	return exportString(field.Value)
+}
+
+// quoteEnv double quotes a value unless it is a plain word, escaping
+// the characters that a [DotEnvSource] and the shell would interpret.
+func quoteEnv(s string) string {
+	plain := !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@+=%", r))
+	})
+	if plain {
+		return s
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// writeJSON writes the nodes as an indented JSON object in order.
+func writeJSON(w io.Writer, nodes []*exportNode) error {
+	var buf bytes.Buffer
+	if err := writeJSONObject(&buf, nodes); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+func writeJSONObject(buf *bytes.Buffer, nodes []*exportNode) error {
+	buf.WriteByte('{')
+	for i, n := range nodes {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(n.key)
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		if len(n.children) > 0 {
+			if err := writeJSONObject(buf, n.children); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, err := json.Marshal(n.value)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", n.key, err)
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func yamlMapping(nodes []*exportNode) (*yaml.Node, error) {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+
+	for _, n := range nodes {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: n.key, HeadComment: n.comment}
+
+		var value *yaml.Node
+		if len(n.children) > 0 {
+			var err error
+			if value, err = yamlMapping(n.children); err != nil {
+				return nil, err
+			}
+		} else {
+			value = &yaml.Node{}
+			if err := value.Encode(n.value); err != nil {
+				return nil, fmt.Errorf("export %s: %w", n.key, err)
+			}
+		}
+
+		mapping.Content = append(mapping.Content, key, value)
+	}
+
+	return mapping, nil
+}
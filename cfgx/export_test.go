@@ -0,0 +1,261 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type exportConfig struct {
+	Port     int           `default:"8080"`
+	Timeout  time.Duration `default:"5s"`
+	Greeting string        `default:"hello world"`
+	Debug    bool          `optional:"true"`
+	DB       struct {
+		Hosts    []string          `default:"a,b"`
+		Password string            `secret:"true" default:"s3cret"`
+		Labels   map[string]string `default:"env=prod,team=core"`
+	}
+	PoolSize int `key:"pool.size" default:"10"`
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	var cfg exportConfig
+	if err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := cfgx.ExportJSON(&out, &cfg, cfgx.ExportOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		want := `{
+  "port": 8080,
+  "timeout": "5s",
+  "greeting": "hello world",
+  "debug": false,
+  "db": {
+    "hosts": [
+      "a",
+      "b"
+    ],
+    "password": "[REDACTED]",
+    "labels": {
+      "env": "prod",
+      "team": "core"
+    }
+  },
+  "pool": {
+    "size": 10
+  }
+}
+`
+		if out.String() != want {
+			t.Errorf("wanted:\n%s\ngot:\n%s", want, out.String())
+		}
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := cfgx.ExportYAML(&out, &cfg, cfgx.ExportOptions{Secrets: cfgx.SecretsOmit}); err != nil {
+			t.Fatal(err)
+		}
+
+		want := `port: 8080
+timeout: 5s
+greeting: hello world
+debug: false
+db:
+  hosts:
+    - a
+    - b
+  labels:
+    env: prod
+    team: core
+pool:
+  size: 10
+`
+		if out.String() != want {
+			t.Errorf("wanted:\n%s\ngot:\n%s", want, out.String())
+		}
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		if err := cfgx.ExportEnv(&out, &cfg, cfgx.ExportOptions{EnvPrefix: "APP"}); err != nil {
+			t.Fatal(err)
+		}
+
+		want := `APP_PORT=8080
+APP_TIMEOUT=5s
+APP_GREETING="hello world"
+APP_DEBUG=false
+APP_DB_HOSTS=a,b
+APP_DB_PASSWORD="[REDACTED]"
+APP_DB_LABELS=env=prod,team=core
+APP_POOL_SIZE=10
+`
+		if out.String() != want {
+			t.Errorf("wanted:\n%s\ngot:\n%s", want, out.String())
+		}
+	})
+
+	t.Run("NotPointer", func(t *testing.T) {
+		t.Parallel()
+
+		if err := cfgx.ExportJSON(&bytes.Buffer{}, cfg, cfgx.ExportOptions{}); err != cfgx.ErrNotPointerToStruct {
+			t.Errorf("expected ErrNotPointerToStruct, got %v", err)
+		}
+	})
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var cfg exportConfig
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		Args:    []string{"--greeting", `say "hi" for $5`, "--debug", "--db.password", "p#ss word"},
+		SkipEnv: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	opts := cfgx.ExportOptions{EnvPrefix: "APP", Secrets: cfgx.SecretsShow}
+
+	tests := map[string]struct {
+		file   string
+		export func(w *bytes.Buffer) error
+		source cfgx.Source
+	}{
+		"JSON": {
+			file:   "config.json",
+			export: func(w *bytes.Buffer) error { return cfgx.ExportJSON(w, &cfg, opts) },
+			source: cfgx.NewFileSource(filepath.Join(dir, "config.json")),
+		},
+		"YAML": {
+			file:   "config.yaml",
+			export: func(w *bytes.Buffer) error { return cfgx.ExportYAML(w, &cfg, opts) },
+			source: cfgx.NewFileSource(filepath.Join(dir, "config.yaml")),
+		},
+		"Env": {
+			file:   ".env",
+			export: func(w *bytes.Buffer) error { return cfgx.ExportEnv(w, &cfg, opts) },
+			source: &cfgx.DotEnvSource{PriorityLevel: cfgx.PriorityDotEnv, Paths: []string{filepath.Join(dir, ".env")}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tc.export(&out); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, tc.file), out.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var got exportConfig
+			err := cfgx.Parse(&got, cfgx.Options{
+				EnvPrefix: "APP",
+				SkipFlags: true,
+				SkipEnv:   true,
+				Sources:   []cfgx.Source{tc.source},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Port != cfg.Port || got.Timeout != cfg.Timeout || got.Greeting != cfg.Greeting ||
+				got.Debug != cfg.Debug || got.PoolSize != cfg.PoolSize || got.DB.Password != cfg.DB.Password {
+				t.Errorf("wanted %+v, got %+v", cfg, got)
+			}
+			if !slices.Equal(got.DB.Hosts, cfg.DB.Hosts) || !maps.Equal(got.DB.Labels, cfg.DB.Labels) {
+				t.Errorf("wanted %+v, got %+v", cfg.DB, got.DB)
+			}
+			if strings.Contains(out.String(), cfgx.Redacted) {
+				t.Errorf("expected the secret in:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestExport_NilPointer(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Timeout *time.Duration `optional:"true"`
+		Retries *int           `optional:"true"`
+	}
+	retries := 3
+	cfg := config{Retries: &retries}
+
+	dir := t.TempDir()
+	tests := map[string]struct {
+		file   string
+		export func(w *bytes.Buffer) error
+		want   string
+		source cfgx.Source
+	}{
+		"JSON": {
+			file:   "config.json",
+			export: func(w *bytes.Buffer) error { return cfgx.ExportJSON(w, &cfg, cfgx.ExportOptions{}) },
+			want:   "{\n  \"timeout\": null,\n  \"retries\": 3\n}\n",
+			source: cfgx.NewFileSource(filepath.Join(dir, "config.json")),
+		},
+		"YAML": {
+			file:   "config.yaml",
+			export: func(w *bytes.Buffer) error { return cfgx.ExportYAML(w, &cfg, cfgx.ExportOptions{}) },
+			want:   "timeout: null\nretries: 3\n",
+			source: cfgx.NewFileSource(filepath.Join(dir, "config.yaml")),
+		},
+		"Env": {
+			file:   ".env",
+			export: func(w *bytes.Buffer) error { return cfgx.ExportEnv(w, &cfg, cfgx.ExportOptions{}) },
+			want:   "RETRIES=3\n",
+			source: &cfgx.DotEnvSource{PriorityLevel: cfgx.PriorityDotEnv, Paths: []string{filepath.Join(dir, ".env")}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tc.export(&out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("wanted %q, got %q", tc.want, out.String())
+			}
+			if err := os.WriteFile(filepath.Join(dir, tc.file), out.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var got config
+			err := cfgx.Parse(&got, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{tc.source}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Timeout != nil || got.Retries == nil || *got.Retries != 3 {
+				t.Errorf("wanted %+v, got %+v", cfg, got)
+			}
+		})
+	}
+}
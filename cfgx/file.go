@@ -0,0 +1,274 @@
+package cfgx
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// File formats supported by [FileSource].
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// ====================================================================
+// Config files
+
+// FileSource reads a structured config file (JSON, YAML or TOML) and maps
+// its nested keys onto the struct paths, so that
+//
+//	db:
+//	  max_conns: 10
+//
+// sets the field DB.MaxConns. Keys are matched case-insensitively and
+// ignoring "_" and "-", so "max_conns", "max-conns" and "maxConns" all
+// match MaxConns.
+// Override the dotted key path with the tag "key", e.g. `key:"database.pool_size"`.
+type FileSource struct {
+	PriorityLevel int
+	// Path is the name of the file. It is relative to FS if FS is set.
+	Path string
+	// FS is the file system to read Path from. If nil, Path is read with [os.ReadFile].
+	FS fs.FS
+	// Format is one of "json", "yaml" or "toml".
+	// If empty it is inferred from the extension of Path.
+	Format string
+	// Optional skips the source if the file does not exist.
+	Optional bool
+}
+
+// NewFileSource sets a priority of PriorityFile (25) and reads
+// the file at path from the OS.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{
+		PriorityLevel: PriorityFile,
+		Path:          path,
+	}
+}
+
+// Priority implements [Source].
+func (s *FileSource) Priority() int {
+	return s.PriorityLevel
+}
+
+// Name implements [NamedSource].
+func (s *FileSource) Name() string {
+	return "file"
+}
+
+// Process implements [Source].
+func (s *FileSource) Process(structMap map[string]ConfigField) error {
+	b, err := s.read()
+	if errors.Is(err, fs.ErrNotExist) && s.Optional {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read config file %s: %w", s.Path, err)
+	}
+
+	format := cmp.Or(s.Format, strings.TrimPrefix(path.Ext(s.Path), "."))
+	data, err := decodeFile(b, format)
+	if err != nil {
+		return fmt.Errorf("decode config file %s: %w", s.Path, err)
+	}
+
+	// Flatten into normalized dotted keys
+	keys := map[string]any{}
+	flattenKeys(keys, "", data)
+
+	var allErrs []error
+
+	for name, field := range structMap {
+		key := name
+
+		// Override the key
+		if tagVal, ok := field.Tag.Lookup(tagKey); ok {
+			key = tagVal
+		}
+
+		val, ok := keys[normalizeKeyPath(key)]
+		if !ok || val == nil {
+			continue
+		}
+
+		// Lists and tables map directly onto slices and maps
+		if field.isList() {
+			if items, ok := formatItems(val); ok {
+				if err := field.SetItems(items); err != nil {
+					allErrs = append(allErrs, err)
+				}
+				continue
+			}
+		}
+
+		raw, ok := formatScalar(val)
+		if !ok {
+			allErrs = append(allErrs, fmt.Errorf("cannot set %s: key %s is not a scalar value", field.Path, key))
+			continue
+		}
+
+		if err := field.Set(raw); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return &MultiError{allErrs}
+	}
+	return nil
+}
+
+// Fingerprint implements [Fingerprinter] with a hash of the file.
+func (s *FileSource) Fingerprint() (string, error) {
+	b, err := s.read()
+	if errors.Is(err, fs.ErrNotExist) {
+		return "missing", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *FileSource) read() ([]byte, error) {
+	if s.FS != nil {
+		return fs.ReadFile(s.FS, s.Path)
+	}
+	return os.ReadFile(s.Path)
+}
+
+// Decode the file contents into a generic map
+func decodeFile(b []byte, format string) (map[string]any, error) {
+	data := map[string]any{}
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&data); err != nil {
+			return nil, err
+		}
+	case FormatYAML, "yml":
+		if err := yaml.Unmarshal(b, &data); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(b, &data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	return data, nil
+}
+
+// Flatten nested maps into dotted keys. Every node is kept, not only
+// the leaves, so a key can also point at a nested map.
+func flattenKeys(out map[string]any, prefix string, v any) {
+	var m map[string]any
+
+	switch v := v.(type) {
+	case map[string]any:
+		m = v
+	case map[any]any:
+		m = make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+	default:
+		return
+	}
+
+	for k, val := range m {
+		key := normalizeKey(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		out[key] = val
+		flattenKeys(out, key, val)
+	}
+}
+
+// Lowercase each segment and strip "_" and "-".
+func normalizeKeyPath(p string) string {
+	segments := strings.Split(p, ".")
+	for i, s := range segments {
+		segments[i] = normalizeKey(s)
+	}
+	return strings.Join(segments, ".")
+}
+
+func normalizeKey(s string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+}
+
+// Format a decoded scalar as the string the field setters expect.
+func formatScalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// Format a decoded list as items, or a decoded map as key=value items.
+func formatItems(v any) ([]string, bool) {
+	switch v := v.(type) {
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, elem := range v {
+			item, ok := formatScalar(elem)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, item)
+		}
+		return items, true
+	case map[string]any:
+		items := make([]string, 0, len(v))
+		for key, elem := range v {
+			item, ok := formatScalar(elem)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, key+"="+item)
+		}
+		return items, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,164 @@
+package cfgx_test
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type fileConfig struct {
+	Port    int
+	BaseURL string
+	Timeout time.Duration
+	DB      struct {
+		Host     string
+		MaxConns int
+	}
+	PoolSize int `key:"database.pool_size"`
+}
+
+func TestFileSource(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"config.json": `{
+			"port": 8080,
+			"base_url": "http://example.com",
+			"timeout": "5s",
+			"db": {"host": "localhost", "max_conns": 10},
+			"database": {"pool_size": 4}
+		}`,
+		"config.yaml": `
+port: 8080
+baseURL: http://example.com
+timeout: 5s
+db:
+  host: localhost
+  max-conns: 10
+database:
+  pool_size: 4
+`,
+		"config.toml": `
+port = 8080
+base_url = "http://example.com"
+timeout = "5s"
+pool_size = 1
+
+[db]
+host = "localhost"
+max_conns = 10
+
+[database]
+pool_size = 4
+`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fsys := fstest.MapFS{name: &fstest.MapFile{Data: []byte(data)}}
+			src := &cfgx.FileSource{PriorityLevel: cfgx.PriorityFile, Path: name, FS: fsys}
+
+			var cfg fileConfig
+			err := cfgx.Parse(&cfg, cfgx.Options{
+				SkipFlags: true,
+				SkipEnv:   true,
+				Sources:   []cfgx.Source{src},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := 8080; cfg.Port != want {
+				t.Errorf("Port: wanted %d, got %d", want, cfg.Port)
+			}
+			if want := "http://example.com"; cfg.BaseURL != want {
+				t.Errorf("BaseURL: wanted %s, got %s", want, cfg.BaseURL)
+			}
+			if want := 5 * time.Second; cfg.Timeout != want {
+				t.Errorf("Timeout: wanted %v, got %v", want, cfg.Timeout)
+			}
+			if want := "localhost"; cfg.DB.Host != want {
+				t.Errorf("DB.Host: wanted %s, got %s", want, cfg.DB.Host)
+			}
+			if want := 10; cfg.DB.MaxConns != want {
+				t.Errorf("DB.MaxConns: wanted %d, got %d", want, cfg.DB.MaxConns)
+			}
+			if want := 4; cfg.PoolSize != want {
+				t.Errorf("PoolSize: wanted %d, got %d", want, cfg.PoolSize)
+			}
+		})
+	}
+}
+
+func TestFileSource_Priority(t *testing.T) {
+	fsys := fstest.MapFS{"config.yaml": &fstest.MapFile{Data: []byte("host: file\nport: 3000\n")}}
+	src := &cfgx.FileSource{PriorityLevel: cfgx.PriorityFile, Path: "config.yaml", FS: fsys}
+
+	var cfg struct {
+		Host string `default:"default"`
+		Port int    `default:"5000"`
+		Name string `default:"default"`
+	}
+
+	os.Setenv("HOST", "env")
+	cleanupEnv(t, "HOST")
+
+	err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, Sources: []cfgx.Source{src}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "env"; cfg.Host != want {
+		t.Errorf("Host: wanted %s, got %s", want, cfg.Host)
+	}
+	if want := 3000; cfg.Port != want {
+		t.Errorf("Port: wanted %d, got %d", want, cfg.Port)
+	}
+	if want := "default"; cfg.Name != want {
+		t.Errorf("Name: wanted %s, got %s", want, cfg.Name)
+	}
+}
+
+func TestFileSource_Missing(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Optional", func(t *testing.T) {
+		t.Parallel()
+		src := &cfgx.FileSource{Path: "missing.yaml", FS: fstest.MapFS{}, Optional: true}
+
+		var cfg struct {
+			Port int `default:"5000"`
+		}
+		if err := src.Process(nil); err != nil {
+			t.Fatal(err)
+		}
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{src}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		t.Parallel()
+		src := &cfgx.FileSource{Path: "missing.yaml", FS: fstest.MapFS{}}
+
+		if err := src.Process(nil); err == nil {
+			t.Fatal("expected error for missing file")
+		}
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{"config.ini": &fstest.MapFile{Data: []byte("port=1")}}
+		src := &cfgx.FileSource{Path: "config.ini", FS: fsys}
+
+		if err := src.Process(nil); err == nil {
+			t.Fatal("expected error for unsupported format")
+		}
+	})
+}
@@ -0,0 +1,415 @@
+package cfgx
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// jsonSchemaDraft is the JSON Schema version written by [WriteJSONSchema].
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaOptional is the annotation of optional fields in the schema.
+// Validators ignore it, but editors and readers can see which fields
+// Parse doesn't require.
+const schemaOptional = "x-cfgx-optional"
+
+// durationPattern matches the strings time.ParseDuration accepts.
+const durationPattern = `^[-+]?(0|([0-9]*(\.[0-9]*)?(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// WriteJSONSchema writes a JSON Schema of the config files a
+// [FileSource] reads, with the same keys as [ExportJSON]. Each field
+// has its type, "desc" tag, default and validation tags, and secrets
+// are marked writeOnly. No field is in "required", since a file is
+// one of several sources and the others can set any field, but fields
+// tagged optional are marked "x-cfgx-optional", so the fields without
+// a default or the annotation are the ones Parse requires.
+func WriteJSONSchema(w io.Writer, cfg any, opts Options) error {
+	fields, err := exampleFields(cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	root := &exportNode{}
+	root.child("$schema").value = jsonSchemaDraft
+	root.child("type").value = "object"
+
+	for _, field := range fields {
+		keys := exportKey(field)
+
+		parent := root
+		for _, key := range keys[:len(keys)-1] {
+			parent = schemaObject(parent, key)
+		}
+
+		node := parent.child("properties").child(keys[len(keys)-1])
+		if err := fieldSchema(node, field); err != nil {
+			return err
+		}
+	}
+
+	return writeJSON(w, root.children)
+}
+
+// WriteEnvExample writes a .env.example file with a variable for each
+// field, as it is read from the environment or a [DotEnvSource], after
+// comments with its description, type and rules. Variables with a
+// default or that are optional are commented out:
+//
+//	# Port to listen on
+//	# int; min 1; max 65535
+//	# APP_PORT=8080
+//
+//	# Database connection string
+//	# string; required
+//	APP_DSN=
+func WriteEnvExample(w io.Writer, cfg any, opts Options) error {
+	fields, err := exampleFields(cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	for i, field := range fields {
+		var b strings.Builder
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if desc := field.Tag.Get(tagDescription); desc != "" {
+			fmt.Fprintf(&b, "# %s\n", desc)
+		}
+		fmt.Fprintf(&b, "# %s\n", fieldNotes(field))
+
+		value := ""
+		if hasDefault(field) && !field.isSecret() {
+			value = quoteEnv(envValue(field))
+		}
+
+		line := envName(field, opts.EnvPrefix) + "=" + value
+		if isOptional(field) || hasDefault(field) {
+			line = "# " + line
+		}
+		b.WriteString(line + "\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteYAMLExample writes a sample YAML config file with the same keys
+// as [ExportYAML], set to the defaults or zero values, and comments
+// with each field's description, type and rules.
+func WriteYAMLExample(w io.Writer, cfg any, opts Options) error {
+	fields, err := exampleFields(cfg, opts)
+	if err != nil {
+		return err
+	}
+
+	root := &exportNode{}
+	for _, field := range fields {
+		node := root
+		for _, key := range exportKey(field) {
+			node = node.child(key)
+		}
+
+		node.comment = fieldNotes(field)
+		if desc := field.Tag.Get(tagDescription); desc != "" {
+			node.comment = desc + "\n" + node.comment
+		}
+
+		node.value = exportValue(field.Value)
+		if field.isSecret() {
+			node.value = reflect.Zero(field.Value.Type()).Interface()
+		}
+	}
+
+	node, err := yamlMapping(root.children)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Generate writes the files named by the flags in args. The cfgxgen
+// command calls it for a config type, or a small program can, to
+// generate them with go generate:
+//
+//	//go:generate go run ./gen -schema config.schema.json -env .env.example -yaml config.example.yaml
+//
+// where gen/main.go is:
+//
+//	func main() {
+//		err := cfgx.Generate(&config.Config{}, cfgx.Options{EnvPrefix: "APP"}, os.Args[1:])
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func Generate(cfg any, opts Options, args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	schema := flags.String("schema", "", "write the JSON Schema to `file`")
+	env := flags.String("env", "", "write the .env example to `file`")
+	yamlFile := flags.String("yaml", "", "write the YAML example to `file`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	files := []struct {
+		path  string
+		write func(io.Writer, any, Options) error
+	}{
+		{*schema, WriteJSONSchema},
+		{*env, WriteEnvExample},
+		{*yamlFile, WriteYAMLExample},
+	}
+
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if err := writeFile(file.path, func(w io.Writer) error { return file.write(w, cfg, opts) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes to a temporary file and renames it to the path,
+// so a failed write leaves the previous file as it was.
+func writeFile(path string, write func(io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err := write(f); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// exampleFields walks a zero value of the config type with the
+// defaults set, in declaration order.
+func exampleFields(cfg any, opts Options) ([]ConfigField, error) {
+	t := reflect.TypeOf(cfg)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotPointerToStruct
+	}
+
+	fields := walkStruct(reflect.New(t.Elem()).Elem(), "", nil, opts.Decoders)
+	if err := (&defaultSource{}).Process(fields); err != nil {
+		return nil, fmt.Errorf("invalid default: %w", err)
+	}
+
+	return slices.DeleteFunc(orderedFields(fields), func(field ConfigField) bool {
+		return field.Tag.Get(tagArgs) == "true"
+	}), nil
+}
+
+func hasDefault(field ConfigField) bool {
+	_, ok := field.Tag.Lookup(tagDefault)
+	return ok
+}
+
+// fieldNotes describes the type and rules of a field, e.g.
+// "int; required; min 1" or "duration; optional".
+func fieldNotes(field ConfigField) string {
+	notes := []string{cmp.Or(typeName(field), "bool")}
+
+	switch {
+	case isOptional(field):
+		notes = append(notes, "optional")
+	case !hasDefault(field):
+		notes = append(notes, "required")
+	}
+	if field.isSecret() {
+		notes = append(notes, "secret")
+	}
+
+	for _, v := range validators {
+		arg, ok := field.Tag.Lookup(v.tag)
+		if !ok {
+			continue
+		}
+		name := v.tag
+		if v.tag == tagOneOf {
+			name = "one of"
+			arg = strings.Join(splitList(arg, defaultSeparator), ", ")
+		}
+		notes = append(notes, name+" "+arg)
+	}
+
+	return strings.Join(notes, "; ")
+}
+
+// schemaObject returns the nested object schema for the key.
+func schemaObject(parent *exportNode, key string) *exportNode {
+	obj := parent.child("properties").child(key)
+	if len(obj.children) == 0 {
+		obj.child("type").value = "object"
+	}
+	return obj
+}
+
+// fieldSchema fills the schema of a field from its type and tags.
+func fieldSchema(n *exportNode, field ConfigField) error {
+	t := field.Value.Type()
+	typeSchema(n, t, field.decoders)
+
+	if desc := field.Tag.Get(tagDescription); desc != "" {
+		n.child("description").value = desc
+	}
+	if field.isSecret() {
+		n.child("writeOnly").value = true
+	} else if hasDefault(field) {
+		n.child("default").value = exportValue(field.Value)
+	}
+
+	// Element rules apply to the items of slices
+	elem, elemType := n, t
+	if field.isList() && field.Kind == reflect.Slice {
+		elem, elemType = n.child("items"), t.Elem()
+	}
+
+	if arg, ok := field.Tag.Lookup(tagMin); ok && !hasUnits(t) {
+		if err := schemaNumber(n, "minimum", field, arg); err != nil {
+			return err
+		}
+	}
+	if arg, ok := field.Tag.Lookup(tagMax); ok && !hasUnits(t) {
+		if err := schemaNumber(n, "maximum", field, arg); err != nil {
+			return err
+		}
+	}
+
+	lengthKeys := map[reflect.Kind][2]string{
+		reflect.String: {"minLength", "maxLength"},
+		reflect.Slice:  {"minItems", "maxItems"},
+		reflect.Array:  {"minItems", "maxItems"},
+		reflect.Map:    {"minProperties", "maxProperties"},
+	}
+	for i, tag := range []string{tagMinLen, tagMaxLen} {
+		arg, ok := field.Tag.Lookup(tag)
+		keys, supported := lengthKeys[field.Kind]
+		if !ok || !supported {
+			continue
+		}
+		size, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("%s: invalid %s tag: %w", field.Path, tag, err)
+		}
+		n.child(keys[i]).value = size
+	}
+
+	if arg, ok := field.Tag.Lookup(tagOneOf); ok {
+		var enum []any
+		for _, s := range splitList(arg, defaultSeparator) {
+			v, err := parseValue(elemType, s, field.decoders)
+			if err != nil {
+				enum = append(enum, s)
+				continue
+			}
+			enum = append(enum, exportValue(v))
+		}
+		elem.child("enum").value = enum
+	}
+	if arg, ok := field.Tag.Lookup(tagPattern); ok {
+		elem.child("pattern").value = arg
+	}
+	switch field.Tag.Get(tagFormat) {
+	case "url":
+		elem.child("format").value = "uri"
+	case "email":
+		elem.child("format").value = "email"
+	}
+
+	if isOptional(field) {
+		n.child(schemaOptional).value = true
+	}
+
+	return nil
+}
+
+// hasUnits reports whether min and max tags of the type have
+// units, e.g. "5s" or "1MiB", so they are not in the schema.
+func hasUnits(t reflect.Type) bool {
+	return t == durationType || t.Implements(quantityType)
+}
+
+// schemaNumber sets a minimum or maximum, checking that the tag is a number.
+func schemaNumber(n *exportNode, key string, field ConfigField, arg string) error {
+	if _, err := strconv.ParseFloat(arg, 64); err != nil {
+		return fmt.Errorf("%s: invalid %s: %w", field.Path, key, err)
+	}
+	n.child(key).value = json.Number(arg)
+	return nil
+}
+
+// typeSchema sets the JSON type of a Go type. Types that are decoded
+// from text, such as durations, are strings.
+func typeSchema(n *exportNode, t reflect.Type, decoders map[reflect.Type]DecodeFunc) {
+	switch {
+	case t == durationType:
+		n.child("type").value = "string"
+		n.child("pattern").value = durationPattern
+		return
+	case t == reflect.TypeFor[url.URL]() || t == reflect.TypeFor[*url.URL]():
+		n.child("type").value = "string"
+		n.child("format").value = "uri"
+		return
+	case hasDecoder(t, decoders):
+		n.child("type").value = "string"
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		n.child("type").value = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n.child("type").value = "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n.child("type").value = "integer"
+		n.child("minimum").value = 0
+	case reflect.Float32, reflect.Float64:
+		n.child("type").value = "number"
+	case reflect.Slice, reflect.Array:
+		n.child("type").value = "array"
+		typeSchema(n.child("items"), t.Elem(), decoders)
+	case reflect.Map:
+		n.child("type").value = "object"
+		typeSchema(n.child("additionalProperties"), t.Elem(), decoders)
+	default:
+		n.child("type").value = "string"
+	}
+}
@@ -0,0 +1,225 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type generateConfig struct {
+	Port    int           `default:"8080" min:"1" max:"65535" desc:"Port to listen on"`
+	Timeout time.Duration `default:"5s"`
+	Level   string        `default:"info" oneof:"debug,info,warn"`
+	Debug   bool          `optional:"true"`
+	DB      struct {
+		DSN      string   `desc:"Database connection string" format:"url"`
+		Password string   `secret:"true" default:"postgres"`
+		Replicas []string `optional:"true" maxlen:"3" pattern:"^db"`
+	}
+}
+
+func TestWriteJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	if err := cfgx.WriteJSONSchema(&out, &generateConfig{}, cfgx.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "port": {
+      "type": "integer",
+      "description": "Port to listen on",
+      "default": 8080,
+      "minimum": 1,
+      "maximum": 65535
+    },
+    "timeout": {
+      "type": "string",
+      "pattern": "^[-+]?(0|([0-9]*(\\.[0-9]*)?(ns|us|µs|μs|ms|s|m|h))+)$",
+      "default": "5s"
+    },
+    "level": {
+      "type": "string",
+      "default": "info",
+      "enum": [
+        "debug",
+        "info",
+        "warn"
+      ]
+    },
+    "debug": {
+      "type": "boolean",
+      "x-cfgx-optional": true
+    },
+    "db": {
+      "type": "object",
+      "properties": {
+        "dsn": {
+          "type": "string",
+          "description": "Database connection string",
+          "format": "uri"
+        },
+        "password": {
+          "type": "string",
+          "writeOnly": true
+        },
+        "replicas": {
+          "type": "array",
+          "items": {
+            "type": "string",
+            "pattern": "^db"
+          },
+          "maxItems": 3,
+          "x-cfgx-optional": true
+        }
+      }
+    }
+  }
+}
+`
+	if out.String() != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, out.String())
+	}
+	if !json.Valid(out.Bytes()) {
+		t.Error("expected valid JSON")
+	}
+
+	// Optional fields are annotated, required ones are not
+	var schema struct {
+		Properties struct {
+			Debug map[string]any `json:"debug"`
+			DB    struct {
+				Properties struct {
+					DSN map[string]any `json:"dsn"`
+				} `json:"properties"`
+			} `json:"db"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Properties.Debug["x-cfgx-optional"] != true {
+		t.Error("expected the optional Debug to be annotated")
+	}
+	if _, ok := schema.Properties.DB.Properties.DSN["x-cfgx-optional"]; ok {
+		t.Error("expected the required DSN not to be annotated")
+	}
+}
+
+func TestWriteEnvExample(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	if err := cfgx.WriteEnvExample(&out, &generateConfig{}, cfgx.Options{EnvPrefix: "APP"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# Port to listen on
+# int; min 1; max 65535
+# APP_PORT=8080
+
+# duration
+# APP_TIMEOUT=5s
+
+# string; one of debug, info, warn
+# APP_LEVEL=info
+
+# bool; optional
+# APP_DEBUG=
+
+# Database connection string
+# string; required; format url
+APP_DB_DSN=
+
+# string; secret
+# APP_DB_PASSWORD=
+
+# []string; optional; maxlen 3; pattern ^db
+# APP_DB_REPLICAS=
+`
+	if out.String() != want {
+		t.Errorf("wanted:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestWriteYAMLExample(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	if err := cfgx.WriteYAMLExample(&out, &generateConfig{}, cfgx.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# Port to listen on\n# int; min 1; max 65535\nport: 8080\n",
+		"# duration\ntimeout: 5s\n",
+		"db:\n  # Database connection string\n  # string; required; format url\n  dsn: \"\"\n",
+		"  # string; secret\n  password: \"\"\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	// The sample reads back, with the required fields filled in
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	sample := strings.NewReplacer(`dsn: ""`, "dsn: postgres://db", `password: ""`, "password: s3cret").Replace(out.String())
+	if err := os.WriteFile(path, []byte(sample), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg generateConfig
+	err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{cfgx.NewFileSource(path)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8080 || cfg.DB.DSN != "postgres://db" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "config.schema.json")
+	env := filepath.Join(dir, ".env.example")
+
+	err := cfgx.Generate(&generateConfig{}, cfgx.Options{EnvPrefix: "APP"}, []string{"-schema", schema, "-env", env})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{schema, env} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.example.yaml")); err == nil {
+		t.Error("expected no YAML example without -yaml")
+	}
+	// A failed write keeps the previous file
+	var invalid struct {
+		Port int `min:"one"`
+	}
+	if err := cfgx.Generate(&invalid, cfgx.Options{}, []string{"-schema", schema}); err == nil {
+		t.Fatal("expected an error for the invalid min tag")
+	}
+	if b, err := os.ReadFile(schema); err != nil || !strings.Contains(string(b), `"port"`) {
+		t.Errorf("expected the previous schema, got %q (%v)", b, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected no temporary files, got %v", entries)
+	}
+}
@@ -0,0 +1,192 @@
+package cfgx
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// gnuParser parses args like GNU getopt_long, into the same
+// [fieldFlag] values as the flag package.
+type gnuParser struct {
+	long   map[string]*fieldFlag // By flag name, and short names longer than a letter
+	short  map[string]*fieldFlag // By single letter short name
+	args   []string              // Not parsed yet
+	strict bool
+	// stop ends parsing at the first positional arg instead of
+	// collecting it and continuing, e.g. for a command name.
+	stop bool
+}
+
+func newGNUParser(values map[string]*fieldFlag, aliases []*aliasFlag, args []string) *gnuParser {
+	p := &gnuParser{
+		long:  map[string]*fieldFlag{},
+		short: map[string]*fieldFlag{},
+		args:  args,
+	}
+
+	for _, value := range values {
+		name, short := flagNames(value.field)
+		p.long[name] = value
+
+		switch {
+		case len(short) == 1:
+			p.short[short] = value
+		case short != "":
+			p.long[short] = value
+		}
+	}
+
+	for _, alias := range aliases {
+		if len(alias.name) == 1 {
+			p.short[alias.name] = alias.value
+		} else {
+			p.long[alias.name] = alias.value
+		}
+	}
+
+	return p
+}
+
+// parse sets the flags and returns the positional args, which can
+// come before, between or after the flags:
+//
+//	--name value, --name=value, --bool, --no-bool
+//	-n value, -nvalue, -abc (bools), -abn value
+//	-- (the rest are positional)
+//
+// It returns [flag.ErrHelp] for -h and --help unless they are defined.
+func (p *gnuParser) parse() ([]string, error) {
+	var rest []string
+
+	for len(p.args) > 0 {
+		arg := p.next()
+
+		var err error
+		switch {
+		case arg == "--":
+			return append(rest, p.args...), nil
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			if p.stop {
+				return append(append(rest, arg), p.args...), nil
+			}
+			rest = append(rest, arg)
+		case strings.HasPrefix(arg, "--"):
+			err = p.parseLong(arg[2:])
+		default:
+			err = p.parseShort(arg[1:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rest, nil
+}
+
+// parseLong parses "name", "name=value" or "no-name" after the "--".
+func (p *gnuParser) parseLong(arg string) error {
+	name, value, hasValue := strings.Cut(arg, "=")
+
+	f, ok := p.long[name]
+	if !ok {
+		// --no-name sets a bool to false
+		if bare, found := strings.CutPrefix(name, "no-"); found {
+			if f, ok := p.long[bare]; ok && f.IsBoolFlag() {
+				if hasValue {
+					return fmt.Errorf("flag does not take a value: --%s", name)
+				}
+				return p.set(f, "--"+name, "false")
+			}
+		}
+		if name == "help" {
+			return flag.ErrHelp
+		}
+		return p.unknown("--" + name)
+	}
+
+	switch {
+	case hasValue:
+	case f.IsBoolFlag():
+		value = "true"
+	default:
+		var err error
+		if value, err = p.value("--" + name); err != nil {
+			return err
+		}
+	}
+
+	return p.set(f, "--"+name, value)
+}
+
+// parseShort parses bundled short flags after the "-". Each letter
+// up to the first that takes a value must be a bool, and the rest
+// of the arg, or the next arg, is the value. Unknown letters are
+// skipped unless strict.
+func (p *gnuParser) parseShort(arg string) error {
+	for i, r := range arg {
+		name := string(r)
+		after := arg[i+len(name):]
+
+		f, ok := p.short[name]
+		switch {
+		case !ok && name == "h":
+			return flag.ErrHelp
+		case !ok:
+			// Skip only the unknown letter, since the rest can be known
+			if err := p.unknown("-" + name); err != nil {
+				return err
+			}
+			continue
+		case f.IsBoolFlag():
+			if err := p.set(f, "-"+name, "true"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value := after
+		if value == "" {
+			var err error
+			if value, err = p.value("-" + name); err != nil {
+				return err
+			}
+		}
+		return p.set(f, "-"+name, value)
+	}
+
+	return nil
+}
+
+func (p *gnuParser) next() string {
+	arg := p.args[0]
+	p.args = p.args[1:]
+	return arg
+}
+
+// value takes the next arg as the value of the flag.
+func (p *gnuParser) value(name string) (string, error) {
+	if len(p.args) == 0 {
+		return "", fmt.Errorf("flag needs an argument: %s", name)
+	}
+	return p.next(), nil
+}
+
+func (p *gnuParser) set(f *fieldFlag, name, value string) error {
+	if err := f.Set(value); err != nil {
+		if f.err != nil {
+			return f.err
+		}
+		return fmt.Errorf("invalid value %q for flag %s: %w", value, name, err)
+	}
+	return nil
+}
+
+// unknown fails in strict mode, and otherwise skips the flag. Like
+// [knownFlags] the next arg is kept, since it could be positional.
+func (p *gnuParser) unknown(name string) error {
+	if p.strict {
+		return fmt.Errorf("flag provided but not defined: %s", name)
+	}
+	return nil
+}
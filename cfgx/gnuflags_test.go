@@ -0,0 +1,247 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type gnuConfig struct {
+	Verbose bool     `short:"v" optional:"true"`
+	Quiet   bool     `short:"q" optional:"true"`
+	Color   bool     `default:"true"`
+	Name    string   `short:"n" default:"world"`
+	Tags    []string `short:"t" optional:"true"`
+	Files   []string `args:"true" optional:"true" desc:"Files to process"`
+}
+
+func TestGNUFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args []string
+		want gnuConfig
+	}{
+		"Long": {
+			args: []string{"--verbose", "--name", "gopher"},
+			want: gnuConfig{Verbose: true, Color: true, Name: "gopher"},
+		},
+		"LongEquals": {
+			args: []string{"--name=gopher", "--verbose=false"},
+			want: gnuConfig{Color: true, Name: "gopher"},
+		},
+		"Negation": {
+			args: []string{"--no-color"},
+			want: gnuConfig{Name: "world"},
+		},
+		"Bundled": {
+			args: []string{"-vq"},
+			want: gnuConfig{Verbose: true, Quiet: true, Color: true, Name: "world"},
+		},
+		"BundledValue": {
+			args: []string{"-vngopher"},
+			want: gnuConfig{Verbose: true, Color: true, Name: "gopher"},
+		},
+		"BundledNextValue": {
+			args: []string{"-qn", "gopher"},
+			want: gnuConfig{Quiet: true, Color: true, Name: "gopher"},
+		},
+		"Repeated": {
+			args: []string{"-t", "a", "--tags=b,c"},
+			want: gnuConfig{Color: true, Name: "world", Tags: []string{"a", "b", "c"}},
+		},
+		"Positional": {
+			args: []string{"a.txt", "-v", "b.txt", "--name", "gopher", "c.txt"},
+			want: gnuConfig{Verbose: true, Color: true, Name: "gopher", Files: []string{"a.txt", "b.txt", "c.txt"}},
+		},
+		"Terminator": {
+			args: []string{"-v", "--", "-q", "--name"},
+			want: gnuConfig{Verbose: true, Color: true, Name: "world", Files: []string{"-q", "--name"}},
+		},
+		"Stdin": {
+			args: []string{"-"},
+			want: gnuConfig{Color: true, Name: "world", Files: []string{"-"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg gnuConfig
+			err := cfgx.Parse(&cfg, cfgx.Options{Args: tc.args, SkipEnv: true, GNUFlags: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Verbose != tc.want.Verbose || cfg.Quiet != tc.want.Quiet || cfg.Color != tc.want.Color || cfg.Name != tc.want.Name {
+				t.Errorf("wanted %+v, got %+v", tc.want, cfg)
+			}
+			if !slices.Equal(cfg.Tags, tc.want.Tags) {
+				t.Errorf("Tags: wanted %v, got %v", tc.want.Tags, cfg.Tags)
+			}
+			if !slices.Equal(cfg.Files, tc.want.Files) {
+				t.Errorf("Files: wanted %v, got %v", tc.want.Files, cfg.Files)
+			}
+		})
+	}
+}
+
+func TestGNUFlags_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args   []string
+		strict bool
+		want   string
+	}{
+		"MissingValue":   {args: []string{"--name"}, want: "flag needs an argument: --name"},
+		"MissingShort":   {args: []string{"-vn"}, want: "flag needs an argument: -n"},
+		"NegateNotBool":  {args: []string{"--no-name"}, strict: true, want: "flag provided but not defined: --no-name"},
+		"NegateValue":    {args: []string{"--no-color=true"}, want: "flag does not take a value: --no-color"},
+		"UnknownStrict":  {args: []string{"--port", "80"}, strict: true, want: "flag provided but not defined: --port"},
+		"UnknownBundled": {args: []string{"-vx"}, strict: true, want: "flag provided but not defined: -x"},
+		"InvalidValue":   {args: []string{"--color=maybe"}, want: "cannot set Color"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg gnuConfig
+			err := cfgx.Parse(&cfg, cfgx.Options{
+				Args:     tc.args,
+				SkipEnv:  true,
+				GNUFlags: true,
+				Strict:   tc.strict,
+				Output:   io.Discard,
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+
+	t.Run("UnknownSkipped", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg gnuConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:     []string{"--port=80", "-x", "--debug", "-v", "a.txt"},
+			SkipEnv:  true,
+			GNUFlags: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.Verbose || !slices.Equal(cfg.Files, []string{"a.txt"}) {
+			t.Errorf("expected the known flags and args, got %+v", cfg)
+		}
+	})
+
+	t.Run("UnknownInBundle", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg gnuConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Args:     []string{"-vxq", "-xnbob", "a.txt"},
+			SkipEnv:  true,
+			GNUFlags: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.Verbose || !cfg.Quiet || cfg.Name != "bob" || !slices.Equal(cfg.Files, []string{"a.txt"}) {
+			t.Errorf("expected the letters after the unknown one, got %+v", cfg)
+		}
+	})
+}
+
+func TestGNUFlags_Help(t *testing.T) {
+	t.Parallel()
+
+	for _, arg := range []string{"-h", "--help"} {
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg gnuConfig
+			var out bytes.Buffer
+			err := cfgx.Parse(&cfg, cfgx.Options{
+				ProgramName: "app",
+				Args:        []string{"-v", arg},
+				SkipEnv:     true,
+				GNUFlags:    true,
+				Output:      &out,
+			})
+			if !errors.Is(err, flag.ErrHelp) {
+				t.Fatalf("expected flag.ErrHelp, got %v", err)
+			}
+
+			for _, want := range []string{"Usage of app:", "-v, --verbose", "files...", "Files to process"} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected %q in:\n%s", want, out.String())
+				}
+			}
+			if strings.Contains(out.String(), "--files") {
+				t.Errorf("expected no flag for the args in:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestArgsField(t *testing.T) {
+	t.Parallel()
+
+	// The args field also works with the flag package, which
+	// stops at the first positional arg
+	var cfg struct {
+		Verbose bool     `optional:"true"`
+		Files   []string `args:"true"`
+	}
+	err := cfgx.Parse(&cfg, cfgx.Options{Args: []string{"-verbose", "a.txt", "-b"}, SkipEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Verbose || !slices.Equal(cfg.Files, []string{"a.txt", "-b"}) {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	// Required unless optional
+	cfg.Files = nil
+	err = cfgx.Parse(&cfg, cfgx.Options{Args: []string{}, SkipEnv: true})
+	var valErr *cfgx.ValidationError
+	if !errors.As(err, &valErr) || valErr.Field != "Files" {
+		t.Errorf("expected a required error for Files, got %v", err)
+	}
+}
+
+func TestParseCommand_GNUFlags(t *testing.T) {
+	t.Parallel()
+
+	var global globalConfig
+	var serve serveConfig
+	var migrate migrateConfig
+
+	// Globals stop at the command, the command's flags may follow its args
+	cmd, args, err := cfgx.ParseCommand(&global, cfgx.Options{
+		Args:     []string{"-v", "serve", "extra", "--port", "9000", "args"},
+		SkipEnv:  true,
+		GNUFlags: true,
+	}, commands(&serve, &migrate)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Name != "serve" || !global.Verbose || serve.Port != 9000 {
+		t.Errorf("unexpected result: %s %+v %+v", cmd.Name, global, serve)
+	}
+	if !slices.Equal(args, []string{"extra", "args"}) {
+		t.Errorf("expected the positional args, got %v", args)
+	}
+}
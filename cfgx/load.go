@@ -0,0 +1,175 @@
+package cfgx
+
+import (
+	"flag"
+	"io"
+	"reflect"
+)
+
+// Option configures [Load].
+type Option func(*Options)
+
+// Load parses a new config of type T, which must be a struct.
+// It is the same as [Parse] with the options applied in order:
+//
+//	cfg, err := cfgx.Load[Config](
+//		cfgx.WithEnvPrefix("APP"),
+//		cfgx.WithSources(cfgx.NewFileSource("config.yaml")),
+//	)
+//
+// On error the config is returned as far as it was parsed.
+func Load[T any](opts ...Option) (T, error) {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var cfg T
+	err := Parse(&cfg, options)
+	return cfg, err
+}
+
+// WithProgramName sets the name used in the usage.
+// Default: os.Args[0]
+func WithProgramName(name string) Option {
+	return func(o *Options) {
+		o.ProgramName = name
+	}
+}
+
+// WithEnvPrefix adds a prefix to environment variable names.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.EnvPrefix = prefix
+	}
+}
+
+// WithArgs sets the command line arguments.
+// Default: os.Args[1:]
+func WithArgs(args ...string) Option {
+	return func(o *Options) {
+		o.Args = args
+	}
+}
+
+// WithSkipFlags ignores command line flags.
+func WithSkipFlags(skip bool) Option {
+	return func(o *Options) {
+		o.SkipFlags = skip
+	}
+}
+
+// WithSkipEnv ignores environment variables.
+func WithSkipEnv(skip bool) Option {
+	return func(o *Options) {
+		o.SkipEnv = skip
+	}
+}
+
+// WithErrorHandling sets how errors are handled.
+// Default: flag.ContinueOnError
+func WithErrorHandling(h flag.ErrorHandling) Option {
+	return func(o *Options) {
+		o.ErrorHandling = h
+	}
+}
+
+// WithSources adds sources. It can be used more than once.
+func WithSources(sources ...Source) Option {
+	return func(o *Options) {
+		o.Sources = append(o.Sources, sources...)
+	}
+}
+
+// WithDecoder registers a decoder for fields of type T.
+func WithDecoder[T any](decode func(raw string) (T, error)) Option {
+	return func(o *Options) {
+		if o.Decoders == nil {
+			o.Decoders = map[reflect.Type]DecodeFunc{}
+		}
+		o.Decoders[reflect.TypeFor[T]()] = func(raw string) (any, error) {
+			return decode(raw)
+		}
+	}
+}
+
+// WithProvenance fills p with the source of each field's value.
+func WithProvenance(p *Provenance) Option {
+	return func(o *Options) {
+		o.Provenance = p
+	}
+}
+
+// WithOutput sets where the usage for -h and --help is written.
+// Default: os.Stderr
+func WithOutput(w io.Writer) Option {
+	return func(o *Options) {
+		o.Output = w
+	}
+}
+
+// WithResolver registers a resolver for references with the scheme.
+func WithResolver(scheme string, resolve ResolveFunc) Option {
+	return func(o *Options) {
+		if o.Resolvers == nil {
+			o.Resolvers = map[string]ResolveFunc{}
+		}
+		o.Resolvers[scheme] = resolve
+	}
+}
+
+// WithDecrypter decrypts values written as "enc:<base64>".
+func WithDecrypter(d Decrypter) Option {
+	return func(o *Options) {
+		o.Decrypter = d
+	}
+}
+
+// WithResolve resolves references and ${} interpolation in every field.
+func WithResolve(resolve bool) Option {
+	return func(o *Options) {
+		o.Resolve = resolve
+	}
+}
+
+// WithStrict fails on unknown flags and on environment variables
+// with the prefix that match no field.
+func WithStrict(strict bool) Option {
+	return func(o *Options) {
+		o.Strict = strict
+	}
+}
+
+// WithProfileSources adds sources that are used when the profile is
+// active. It can be used more than once.
+func WithProfileSources(profile string, sources ...Source) Option {
+	return func(o *Options) {
+		if o.ProfileSources == nil {
+			o.ProfileSources = map[string][]Source{}
+		}
+		o.ProfileSources[profile] = append(o.ProfileSources[profile], sources...)
+	}
+}
+
+// WithGNUFlags parses flags like GNU tools, with --name, -n,
+// bundled short flags and --no-name for bools.
+func WithGNUFlags(gnu bool) Option {
+	return func(o *Options) {
+		o.GNUFlags = gnu
+	}
+}
+
+// WithOnDeprecated handles fields set with a deprecated name,
+// instead of logging a warning.
+func WithOnDeprecated(fn func(Deprecation)) Option {
+	return func(o *Options) {
+		o.OnDeprecated = fn
+	}
+}
+
+// WithEnv replaces the process environment with the variables.
+func WithEnv(env map[string]string) Option {
+	return func(o *Options) {
+		o.Env = env
+	}
+}
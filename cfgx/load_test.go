@@ -0,0 +1,59 @@
+package cfgx_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type level int
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Port  int    `default:"8080"`
+		Host  string `default:"localhost"`
+		Level level  `default:"warn"`
+		Token string `default:"vault:api" secret:"true"`
+	}
+
+	var prov cfgx.Provenance
+	cfg, err := cfgx.Load[config](
+		cfgx.WithArgs("--port", "9000"),
+		cfgx.WithSkipEnv(true),
+		cfgx.WithProvenance(&prov),
+		cfgx.WithDecoder(func(raw string) (level, error) {
+			levels := map[string]level{"debug": 0, "info": 1, "warn": 2}
+			if l, ok := levels[raw]; ok {
+				return l, nil
+			}
+			return 0, errors.New("unknown level")
+		}),
+		cfgx.WithResolve(true),
+		cfgx.WithResolver("vault", func(ref string) (string, error) {
+			return strings.ToUpper(ref), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Port != 9000 || cfg.Host != "localhost" || cfg.Level != 2 || cfg.Token != "API" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if f, _ := prov.Field("Port"); f.Source == nil || f.Source.Source != "flag" {
+		t.Errorf("Port: wanted source flag, got %+v", f.Source)
+	}
+}
+
+func TestLoad_NotStruct(t *testing.T) {
+	t.Parallel()
+
+	_, err := cfgx.Load[string](cfgx.WithSkipFlags(true))
+	if !errors.Is(err, cfgx.ErrNotPointerToStruct) {
+		t.Errorf("expected ErrNotPointerToStruct, got %v", err)
+	}
+}
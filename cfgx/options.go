@@ -15,6 +15,7 @@ var DefaultConfigOptions = Options{
 	Args:          os.Args[1:],
 	ErrorHandling: flag.ContinueOnError,
 	Sources:       []Source{},
+	Output:        os.Stderr,
 }
 
 func setOptions(options Options) Options {
@@ -51,5 +52,53 @@ func setOptions(options Options) Options {
 		opts.Sources = append(opts.Sources, options.Sources...)
 	}
 
+	if len(options.Decoders) > 0 {
+		opts.Decoders = options.Decoders
+	}
+
+	if options.Provenance != nil {
+		opts.Provenance = options.Provenance
+	}
+
+	if options.Output != nil {
+		opts.Output = options.Output
+	}
+
+	if options.StructValues {
+		opts.StructValues = true
+	}
+
+	if len(options.Resolvers) > 0 {
+		opts.Resolvers = options.Resolvers
+	}
+
+	if options.Decrypter != nil {
+		opts.Decrypter = options.Decrypter
+	}
+
+	if options.Resolve {
+		opts.Resolve = true
+	}
+
+	if options.Strict {
+		opts.Strict = true
+	}
+
+	if options.GNUFlags {
+		opts.GNUFlags = true
+	}
+
+	if len(options.ProfileSources) > 0 {
+		opts.ProfileSources = options.ProfileSources
+	}
+
+	if options.OnDeprecated != nil {
+		opts.OnDeprecated = options.OnDeprecated
+	}
+
+	if options.Env != nil {
+		opts.Env = options.Env
+	}
+
 	return opts
 }
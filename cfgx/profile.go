@@ -0,0 +1,92 @@
+package cfgx
+
+import (
+	"cmp"
+	"slices"
+)
+
+const (
+	tagProfile = "profile" // Set to "true" on the field that selects the profile
+)
+
+// activeProfile returns the value of the field tagged profile, after
+// all sources ran, or an empty string.
+func activeProfile(fields map[string]ConfigField) string {
+	for _, field := range orderedFields(fields) {
+		if field.Tag.Get(tagProfile) == "true" {
+			return stringOf(field.Value)
+		}
+	}
+	return ""
+}
+
+// profileSources returns the sources of the profile in priority order:
+// the "default.<profile>" tags and the profile's [Options.ProfileSources].
+func profileSources(profile string, opts Options) []Source {
+	sources := []Source{&profileDefaultSource{priority: PriorityDefault, profile: profile}}
+	for _, source := range opts.ProfileSources[profile] {
+		sources = append(sources, &profileSource{Source: source, profile: profile})
+	}
+
+	slices.SortStableFunc(sources, func(a, b Source) int {
+		return cmp.Compare(a.Priority(), b.Priority())
+	})
+	return sources
+}
+
+// processProfile layers the profile's sources over the values that were
+// set by sources with the same or a lower priority, so that a
+// "default.prod" tag overrides the "default" tag but not the environment.
+func (t *tracer) processProfile(source Source, fields map[string]ConfigField) error {
+	layered := make(map[string]ConfigField, len(fields))
+	for path, field := range fields {
+		if origins := t.origins[path]; len(origins) == 0 || origins[len(origins)-1].Priority <= source.Priority() {
+			layered[path] = field
+		}
+	}
+	return t.process(source, layered)
+}
+
+// profileDefaultSource sets the "default.<profile>" tags.
+type profileDefaultSource struct {
+	priority int
+	profile  string
+}
+
+func (s *profileDefaultSource) Priority() int {
+	return s.priority
+}
+
+func (s *profileDefaultSource) Name() string {
+	return tagDefault + "." + s.profile
+}
+
+func (s *profileDefaultSource) Process(fields map[string]ConfigField) error {
+	var allErrs []error
+
+	for _, field := range fields {
+		defVal, ok := field.Tag.Lookup(s.Name())
+		if !ok {
+			continue
+		}
+
+		if err := field.Set(defVal); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	if len(allErrs) > 0 {
+		return &MultiError{allErrs}
+	}
+	return nil
+}
+
+// profileSource names a source of [Options.ProfileSources]
+// with the profile, e.g. "file.prod".
+type profileSource struct {
+	Source
+	profile string
+}
+
+func (s *profileSource) Name() string {
+	return sourceName(s.Source) + "." + s.profile
+}
@@ -0,0 +1,121 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type profileConfig struct {
+	Env      string `profile:"true" default:"dev"`
+	Port     int    `default:"8080" default.prod:"80"`
+	LogLevel string `default:"debug" default.prod:"warn" default.staging:"info"`
+	Host     string `default:"localhost"`
+}
+
+func TestProfile(t *testing.T) {
+	dir := t.TempDir()
+	prodFile := filepath.Join(dir, "config.prod.yaml")
+	if err := os.WriteFile(prodFile, []byte("host: prod.internal\nport: 443\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	baseFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(baseFile, []byte("host: base.internal\nlog_level: error\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Base", func(t *testing.T) {
+		var cfg profileConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Env != "dev" || cfg.Port != 8080 || cfg.LogLevel != "debug" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		var cfg profileConfig
+		var prov cfgx.Provenance
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{"--env", "staging"}, Provenance: &prov})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Port != 8080 || cfg.LogLevel != "info" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+
+		level, _ := prov.Field("LogLevel")
+		if level.Source.Source != "default.staging" || level.Overridden[0].Source != "default" {
+			t.Errorf("unexpected LogLevel provenance: %+v", level)
+		}
+	})
+
+	t.Run("Layered", func(t *testing.T) {
+		os.Setenv("PROF_ENV", "prod")
+		os.Setenv("PROF_PORT", "9000")
+		cleanupEnv(t, "PROF_ENV", "PROF_PORT")
+
+		var cfg profileConfig
+		var prov cfgx.Provenance
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix:      "PROF",
+			Args:           []string{},
+			Sources:        []cfgx.Source{cfgx.NewFileSource(baseFile)},
+			ProfileSources: map[string][]cfgx.Source{"prod": {cfgx.NewFileSource(prodFile)}},
+			Provenance:     &prov,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The profile file overrides the base file and the profile
+		// defaults, but not the environment
+		if cfg.Host != "prod.internal" {
+			t.Errorf("Host: wanted prod.internal, got %s", cfg.Host)
+		}
+		if cfg.Port != 9000 {
+			t.Errorf("Port: wanted 9000, got %d", cfg.Port)
+		}
+		// A profile default does not override the base file
+		if cfg.LogLevel != "error" {
+			t.Errorf("LogLevel: wanted error, got %s", cfg.LogLevel)
+		}
+
+		if prov.Profile != "prod" {
+			t.Errorf("expected the profile in the provenance, got %q", prov.Profile)
+		}
+		host, _ := prov.Field("Host")
+		if host.Source.Source != "file.prod" || len(host.Overridden) != 2 {
+			t.Errorf("unexpected Host provenance: %+v", host)
+		}
+		port, _ := prov.Field("Port")
+		if port.Source.Source != "env" || len(port.Overridden) != 1 {
+			t.Errorf("unexpected Port provenance: %+v", port)
+		}
+
+		var buf bytes.Buffer
+		if err := prov.WriteTable(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buf.String(), "PROFILE: prod\n") {
+			t.Errorf("expected the profile above the table:\n%s", buf.String())
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		var cfg struct {
+			Env  string `profile:"true"`
+			Port int    `default:"8080" default.prod:"eighty"`
+		}
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{"--env=prod"}})
+		if err == nil || !strings.Contains(err.Error(), "default.prod: cannot set Port") {
+			t.Errorf("expected a default.prod error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package cfgx
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// NamedSource is implemented by sources that report a name in the
+// [Provenance]. Other sources are named by their type.
+type NamedSource interface {
+	Source
+	Name() string
+}
+
+// Origin identifies a source that supplied a value.
+type Origin struct {
+	Source   string `json:"source"`
+	Priority int    `json:"priority"`
+}
+
+// String returns the name and priority, e.g. "env (50)".
+func (o Origin) String() string {
+	return fmt.Sprintf("%s (%d)", o.Source, o.Priority)
+}
+
+// FieldProvenance records which source supplied the final value of a
+// field and which sources it overrode, from lowest to highest priority.
+type FieldProvenance struct {
+	Path       string   `json:"path"`
+	Value      any      `json:"value"`
+	Source     *Origin  `json:"source"`
+	Overridden []Origin `json:"overridden,omitempty"`
+}
+
+// Provenance reports where each field's value came from.
+// Pass a pointer in [Options.Provenance] to have Parse fill it.
+// It encodes to JSON with [encoding/json].
+type Provenance struct {
+	// Profile is the active profile, if any.
+	Profile string            `json:"profile,omitempty"`
+	Fields  []FieldProvenance `json:"fields"`
+}
+
+// Field returns the provenance of the field at the dotted path.
+func (p *Provenance) Field(path string) (FieldProvenance, bool) {
+	for _, f := range p.Fields {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return FieldProvenance{}, false
+}
+
+// WriteTable writes the provenance as an aligned table for startup logs
+// or an --explain-config flag. Fields no source set show "-".
+// The active profile, if any, is written above the table.
+func (p *Provenance) WriteTable(w io.Writer) error {
+	if p.Profile != "" {
+		fmt.Fprintf(w, "PROFILE: %s\n\n", p.Profile)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "FIELD\tVALUE\tSOURCE\tOVERRIDES")
+	for _, f := range p.Fields {
+		source := "-"
+		if f.Source != nil {
+			source = f.Source.String()
+		}
+
+		overridden := make([]string, len(f.Overridden))
+		for i, o := range f.Overridden {
+			overridden[i] = o.String()
+		}
+
+		fmt.Fprintf(tw, "%s\t%v\t%s\t%s\n", f.Path, f.Value, source, strings.Join(overridden, ", "))
+	}
+
+	return tw.Flush()
+}
+
+// fieldState is shared by the copies of a ConfigField.
+type fieldState struct {
+	// set is true when the field was set by the current source.
+	set bool
+	// provided is true when the field was set by any source,
+	// even to its zero value.
+	provided bool
+	// resolver resolves encrypted values, and references and ${}
+	// interpolation if refs is true.
+	resolver *resolver
+	refs     bool
+	// pending is the last raw value with references to resolve,
+	// and failed is true when it could not be resolved.
+	pending *pendingValue
+	failed  bool
+	// onDeprecated handles a deprecated name, if not logged.
+	onDeprecated func(Deprecation)
+}
+
+// tracer records which sources set each field, and the fields
+// set by a source that their sources tag does not allow.
+type tracer struct {
+	origins    map[string][]Origin
+	disallowed []error
+}
+
+func newTracer() *tracer {
+	return &tracer{origins: map[string][]Origin{}}
+}
+
+// process calls Process on the source and records it for every field
+// it set, either with [ConfigField.Set] or by changing the value directly.
+func (t *tracer) process(source Source, fields map[string]ConfigField) error {
+	before := make(map[string]any, len(fields))
+	for path, field := range fields {
+		before[path] = field.Value.Interface()
+		field.state.set = false
+	}
+
+	err := source.Process(fields)
+
+	origin := Origin{Source: sourceName(source), Priority: source.Priority()}
+	for path, field := range fields {
+		if field.state.set || !reflect.DeepEqual(before[path], field.Value.Interface()) {
+			t.origins[path] = append(t.origins[path], origin)
+			field.state.provided = true
+
+			if !field.allowsSource(origin.Source) {
+				t.disallowed = append(t.disallowed, field.disallowedError(origin.Source))
+			}
+		}
+	}
+
+	return err
+}
+
+// last returns the name of the last source that set the field.
+func (t *tracer) last(path string) string {
+	origins := t.origins[path]
+	if len(origins) == 0 {
+		return ""
+	}
+	return origins[len(origins)-1].Source
+}
+
+// provenance builds the report from the recorded origins.
+func (t *tracer) provenance(fields map[string]ConfigField) Provenance {
+	var p Provenance
+
+	for _, path := range slices.Sorted(maps.Keys(fields)) {
+		fp := FieldProvenance{
+			Path:  path,
+			Value: fields[path].display(),
+		}
+
+		if origins := t.origins[path]; len(origins) > 0 {
+			last := origins[len(origins)-1]
+			fp.Source = &last
+			fp.Overridden = origins[:len(origins)-1]
+		}
+
+		p.Fields = append(p.Fields, fp)
+	}
+
+	return p
+}
+
+func sourceName(s Source) string {
+	if named, ok := s.(NamedSource); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", s)
+}
@@ -0,0 +1,115 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+func TestProvenance(t *testing.T) {
+	var cfg struct {
+		Version string
+		Port    int    `default:"5000"`
+		Debug   bool   `default:"false" optional:"true"`
+		Name    string `optional:"true"`
+		DB      struct {
+			Host string `default:"localhost"`
+		}
+		Timeout int `optional:"true"`
+	}
+
+	os.Setenv("PORT", "5001")
+	os.Setenv("DB_HOST", "db.internal")
+	os.Setenv("DEBUG", "false")
+	cleanupEnv(t, "PORT", "DB_HOST", "DEBUG")
+
+	custom := sourceFunc(func(fields map[string]cfgx.ConfigField) error {
+		// Set directly instead of with ConfigField.Set
+		fields["Timeout"].Value.SetInt(30)
+		return nil
+	})
+
+	var prov cfgx.Provenance
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		Args:       []string{"--port=3000"},
+		Sources:    []cfgx.Source{custom},
+		Provenance: &prov,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		source     string
+		overridden []string
+	}{
+		{"Version", "build", nil},
+		{"Port", "flag", []string{"default", "env"}},
+		{"Debug", "env", []string{"default"}},
+		{"DB.Host", "env", []string{"default"}},
+		{"Timeout", "cfgx_test.sourceFunc", nil},
+		{"Name", "", nil},
+	}
+
+	for _, tt := range tests {
+		fp, ok := prov.Field(tt.path)
+		if !ok {
+			t.Errorf("%s: missing from provenance", tt.path)
+			continue
+		}
+
+		if tt.source == "" {
+			if fp.Source != nil {
+				t.Errorf("%s: wanted no source, got %s", tt.path, fp.Source)
+			}
+			continue
+		}
+
+		if fp.Source == nil || fp.Source.Source != tt.source {
+			t.Errorf("%s: wanted source %s, got %v", tt.path, tt.source, fp.Source)
+		}
+
+		var overridden []string
+		for _, o := range fp.Overridden {
+			overridden = append(overridden, o.Source)
+		}
+		if !slices.Equal(overridden, tt.overridden) {
+			t.Errorf("%s: wanted overridden %v, got %v", tt.path, tt.overridden, overridden)
+		}
+	}
+
+	if fp, _ := prov.Field("Port"); fp.Value != 3000 || fp.Source.Priority != cfgx.PriorityFlags {
+		t.Errorf("Port: wanted 3000 from priority %d, got %v from %v", cfgx.PriorityFlags, fp.Value, fp.Source)
+	}
+
+	t.Run("Table", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := prov.WriteTable(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		out := buf.String()
+		for _, want := range []string{"FIELD", "Port", "flag (100)", "default (0), env (50)"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("table missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		b, err := json.Marshal(&prov)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(b), `"path":"DB.Host","value":"db.internal","source":{"source":"env","priority":50}`) {
+			t.Errorf("unexpected JSON: %s", b)
+		}
+	})
+}
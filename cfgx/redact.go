@@ -0,0 +1,94 @@
+package cfgx
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+)
+
+// Redacted replaces the value of fields tagged secret wherever
+// cfgx emits values.
+const Redacted = "[REDACTED]"
+
+// isSecret reports whether the field is tagged secret.
+func (f ConfigField) isSecret() bool {
+	return isSecretTag(f.Tag)
+}
+
+func isSecretTag(tag reflect.StructTag) bool {
+	val, exists := tag.Lookup(tagSecret)
+	return exists && val != "false"
+}
+
+// display returns the value to emit, masked if the field is secret.
+// Unset secrets are shown as is so it is clear they are missing.
+func (f ConfigField) display() any {
+	if f.isSecret() && !f.Value.IsZero() {
+		return Redacted
+	}
+	return f.Value.Interface()
+}
+
+// redactedError hides the message of an error that may contain a
+// secret value. It matches the sentinel errors in the chain with
+// [errors.Is], but doesn't unwrap to them, since an error such as
+// *strconv.NumError holds the value.
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return "invalid value " + Redacted
+}
+
+func (e *redactedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+// Redact returns a [slog.LogValuer] for the config struct (or a pointer
+// to it) that logs each field as an attribute and nested structs as groups,
+// with fields tagged secret masked.
+//
+//	slog.Info("loaded config", "config", cfgx.Redact(&cfg))
+func Redact(cfg any) slog.LogValuer {
+	return redactor{reflect.ValueOf(cfg)}
+}
+
+type redactor struct {
+	v reflect.Value
+}
+
+// LogValue implements [slog.LogValuer].
+func (r redactor) LogValue() slog.Value {
+	v := reflect.Indirect(r.v)
+	if v.Kind() != reflect.Struct {
+		return slog.AnyValue(r.v.Interface())
+	}
+	return redactStruct(v)
+}
+
+func redactStruct(v reflect.Value) slog.Value {
+	t := v.Type()
+	attrs := make([]slog.Attr, 0, t.NumField())
+
+	for i := range t.NumField() {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		fieldVal := v.Field(i)
+		name := structField.Name
+
+		switch {
+		case isSecretTag(structField.Tag) && !fieldVal.IsZero():
+			attrs = append(attrs, slog.String(name, Redacted))
+		case fieldVal.Kind() == reflect.Struct && !hasDecoder(fieldVal.Type(), nil):
+			attrs = append(attrs, slog.Attr{Key: name, Value: redactStruct(fieldVal)})
+		default:
+			attrs = append(attrs, slog.Any(name, fieldVal.Interface()))
+		}
+	}
+
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,118 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type secretConfig struct {
+	User     string `default:"admin"`
+	Password string `default:"hunter2" secret:"true" minlen:"10"`
+	APIKey   string `secret:"true" optional:"true"`
+	DB       struct {
+		Host  string `default:"localhost"`
+		Token string `default:"tok-123" secret:"true"`
+	}
+}
+
+func TestSecret(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ValidationError", func(t *testing.T) {
+		t.Parallel()
+		var cfg secretConfig
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+
+		var valErr *cfgx.ValidationError
+		if !errors.As(err, &valErr) {
+			t.Fatalf("wanted ValidationError, got %v", err)
+		}
+		if valErr.Value != cfgx.Redacted {
+			t.Errorf("Value: wanted %s, got %v", cfgx.Redacted, valErr.Value)
+		}
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("error leaks secret: %v", err)
+		}
+	})
+
+	t.Run("SetError", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			PIN int `secret:"true"`
+		}
+
+		var setErr error
+		src := sourceFunc(func(fields map[string]cfgx.ConfigField) error {
+			setErr = fields["PIN"].Set("12ab")
+			return setErr
+		})
+
+		cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Sources: []cfgx.Source{src}})
+
+		if setErr == nil {
+			t.Fatal("wanted error")
+		}
+		if strings.Contains(setErr.Error(), "12ab") {
+			t.Errorf("error leaks secret: %v", setErr)
+		}
+		if !errors.Is(setErr, strconv.ErrSyntax) {
+			t.Errorf("wanted wrapped strconv.ErrSyntax, got %v", setErr)
+		}
+		// The cause holds the value
+		var numErr *strconv.NumError
+		if errors.As(setErr, &numErr) {
+			t.Errorf("error exposes the secret in its cause: %v", numErr)
+		}
+	})
+
+	t.Run("Provenance", func(t *testing.T) {
+		t.Parallel()
+		var cfg secretConfig
+		cfg.Password = "correct horse battery"
+
+		var prov cfgx.Provenance
+		cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Provenance: &prov})
+
+		b, _ := json.Marshal(&prov)
+		if strings.Contains(string(b), "tok-123") {
+			t.Errorf("provenance leaks secret: %s", b)
+		}
+		if fp, _ := prov.Field("APIKey"); fp.Value != "" {
+			t.Errorf("APIKey: wanted empty value for unset secret, got %v", fp.Value)
+		}
+	})
+
+	t.Run("Redact", func(t *testing.T) {
+		t.Parallel()
+		var cfg secretConfig
+		cfg.Password = "correct horse battery"
+
+		if err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+		logger.Info("config", "config", cfgx.Redact(&cfg))
+
+		out := buf.String()
+		for _, leak := range []string{"correct horse", "tok-123"} {
+			if strings.Contains(out, leak) {
+				t.Errorf("log leaks %q: %s", leak, out)
+			}
+		}
+		for _, want := range []string{"config.User=admin", "config.DB.Host=localhost", "config.DB.Token=[REDACTED]"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("log missing %q: %s", want, out)
+			}
+		}
+	})
+}
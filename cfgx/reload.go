@@ -0,0 +1,292 @@
+package cfgx
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	tagReload = "reload" // Set to "false" to reject changes on reload
+
+	defaultReloadInterval = 5 * time.Second
+)
+
+// Fingerprinter is implemented by sources whose data can change while
+// the program runs, such as files. A [Reloader] polls the fingerprint
+// and reloads when it changes.
+type Fingerprinter interface {
+	Fingerprint() (string, error)
+}
+
+// Change is a field whose value changed on reload.
+// Values of fields tagged secret are redacted.
+type Change struct {
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+// Reloader holds a config that can be re-parsed while the program runs.
+// Each reload runs all sources into a fresh struct and validates it, and
+// only swaps it in if it succeeds. Fields tagged `reload:"false"` cannot
+// change; a reload that changes them is rejected with an error.
+type Reloader[T any] struct {
+	// Interval is how often sources implementing [Fingerprinter] are
+	// polled by Watch (defaults to 5s).
+	Interval time.Duration
+	// OnError is called by Watch when a reload fails
+	// (defaults to logging with slog).
+	OnError func(error)
+
+	opts     Options
+	initial  T
+	current  atomic.Pointer[T]
+	mu       sync.Mutex
+	onChange []func(prev, next *T, changes []Change)
+	prints   map[int]string // Fingerprints by index in watched
+}
+
+// NewReloader parses the config into a copy of initial and returns a
+// Reloader holding it. Fields already set in initial are kept on every
+// reload, the same as with [Parse].
+func NewReloader[T any](initial T, opts Options) (*Reloader[T], error) {
+	r := &Reloader[T]{
+		opts:    opts,
+		initial: initial,
+		prints:  map[int]string{},
+	}
+
+	// Fingerprint before parsing so a change during it is not missed
+	r.fingerprint()
+
+	cfg := initial
+	if err := Parse(&cfg, opts); err != nil {
+		return nil, err
+	}
+	r.current.Store(&cfg)
+
+	return r, nil
+}
+
+// Current returns the current config. It must not be modified.
+func (r *Reloader[T]) Current() *T {
+	return r.current.Load()
+}
+
+// OnChange registers a callback that is called after a reload swaps
+// in a config with changes. Callbacks are called without holding the
+// Reloader's lock, so they can call Current, OnChange or Reload.
+func (r *Reloader[T]) OnChange(fn func(prev, next *T, changes []Change)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// Reload parses the config again and swaps it in if it is valid and
+// no field tagged `reload:"false"` changed. It returns the changes.
+func (r *Reloader[T]) Reload() ([]Change, error) {
+	prev, next, changes, err := r.swap()
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+
+	// Call the callbacks without the lock, so they can use the Reloader
+	r.mu.Lock()
+	onChange := slices.Clone(r.onChange)
+	r.mu.Unlock()
+
+	for _, fn := range onChange {
+		fn(prev, next, changes)
+	}
+
+	return changes, nil
+}
+
+// swap parses the config and swaps it in if it changed, returning
+// the previous and new config.
+func (r *Reloader[T]) swap() (prev, next *T, changes []Change, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Never exit or panic on a failed reload
+	opts := r.opts
+	opts.ErrorHandling = flag.ContinueOnError
+	opts.Provenance = nil
+
+	cfg := r.initial
+	if err := Parse(&cfg, opts); err != nil {
+		return nil, nil, nil, fmt.Errorf("reload: %w", err)
+	}
+
+	prev = r.current.Load()
+	changes, errs := diffConfig(reflect.ValueOf(prev).Elem(), reflect.ValueOf(&cfg).Elem(), opts.Decoders)
+	if len(errs) > 0 {
+		return nil, nil, nil, fmt.Errorf("reload: %w", &MultiError{errs})
+	}
+	if len(changes) == 0 {
+		return prev, prev, nil, nil
+	}
+
+	r.current.Store(&cfg)
+	return prev, &cfg, changes, nil
+}
+
+// Watch reloads on SIGHUP and when the fingerprint of a source changes,
+// until the context is done. Failed reloads are passed to OnError.
+func (r *Reloader[T]) Watch(ctx context.Context) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(cmp.Or(r.Interval, defaultReloadInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hup:
+			r.fingerprint()
+			r.reload()
+		case <-ticker.C:
+			if r.fingerprint() {
+				r.reload()
+			}
+		}
+	}
+}
+
+func (r *Reloader[T]) reload() {
+	if _, err := r.Reload(); err != nil {
+		if r.OnError != nil {
+			r.OnError(err)
+			return
+		}
+		slog.Error("Error reloading config.", "error", err)
+	}
+}
+
+// fingerprint updates the fingerprints of the sources and reports
+// whether any changed. Sources that fail are skipped.
+func (r *Reloader[T]) fingerprint() bool {
+	changed := false
+
+	for i, source := range r.watched() {
+		fp, ok := source.(Fingerprinter)
+		if !ok {
+			continue
+		}
+
+		sum, err := fp.Fingerprint()
+		if err != nil {
+			continue
+		}
+
+		if prev, ok := r.prints[i]; ok && prev != sum {
+			changed = true
+		}
+		r.prints[i] = sum
+	}
+
+	return changed
+}
+
+// watched returns the sources and the sources of every profile, in a stable order.
+func (r *Reloader[T]) watched() []Source {
+	sources := slices.Clone(r.opts.Sources)
+	for _, profile := range slices.Sorted(maps.Keys(r.opts.ProfileSources)) {
+		sources = append(sources, r.opts.ProfileSources[profile]...)
+	}
+	return sources
+}
+
+// diffConfig compares every field of the two structs and returns the
+// changes, and an error for each changed field tagged `reload:"false"`.
+func diffConfig(prev, next reflect.Value, decoders map[reflect.Type]DecodeFunc) ([]Change, []error) {
+	fields := walkStruct(reflect.New(prev.Type()).Elem(), "", nil, decoders)
+
+	var changes []Change
+	var errs []error
+
+	for _, field := range orderedFields(fields) {
+		oldField, newField := field, field
+		oldField.Value = prev.FieldByIndex(field.index)
+		newField.Value = next.FieldByIndex(field.index)
+
+		if reflect.DeepEqual(oldField.Value.Interface(), newField.Value.Interface()) {
+			continue
+		}
+
+		if field.Tag.Get(tagReload) == "false" {
+			errs = append(errs, &ValidationError{
+				Field:  field.Path,
+				Value:  newField.display(),
+				Reason: "cannot be changed by a reload",
+			})
+			continue
+		}
+
+		changes = append(changes, Change{
+			Path: field.Path,
+			Old:  oldField.display(),
+			New:  newField.display(),
+		})
+	}
+
+	return changes, errs
+}
+
+// fingerprintFiles hashes the names and contents of the files.
+// Missing files are part of the fingerprint so creating one is a change.
+func fingerprintFiles(fsys fs.FS, names []string) (string, error) {
+	h := sha256.New()
+
+	for _, name := range slices.Sorted(slices.Values(names)) {
+		b, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(h, "%s\x00missing\x00", name)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(b))
+		h.Write(b)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fingerprintDir hashes the regular files at the root of the file system.
+func fingerprintDir(fsys fs.FS) (string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+
+	return fingerprintFiles(fsys, names)
+}
@@ -0,0 +1,206 @@
+package cfgx_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type reloadConfig struct {
+	Level    string `default:"info" oneof:"debug,info,warn"`
+	Port     int    `default:"8080" reload:"false"`
+	Password string `default:"a" secret:"true"`
+}
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+
+	newReloader := func(t *testing.T, fsys fstest.MapFS) *cfgx.Reloader[reloadConfig] {
+		t.Helper()
+		r, err := cfgx.NewReloader(reloadConfig{}, cfgx.Options{
+			SkipFlags: true,
+			SkipEnv:   true,
+			Sources: []cfgx.Source{
+				&cfgx.FileContentSource{PriorityLevel: cfgx.PrioritySecrets, FS: fsys},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("Changes", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{}
+		r := newReloader(t, fsys)
+		first := r.Current()
+
+		var got []cfgx.Change
+		r.OnChange(func(prev, next *reloadConfig, changes []cfgx.Change) {
+			if prev != first || next.Level != "debug" {
+				t.Errorf("callback: wanted prev=first and next.Level=debug, got %+v %+v", prev, next)
+			}
+			got = changes
+		})
+
+		fsys["level"] = &fstest.MapFile{Data: []byte("debug")}
+		fsys["password"] = &fstest.MapFile{Data: []byte("b")}
+
+		changes, err := r.Reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []cfgx.Change{
+			{Path: "Level", Old: "info", New: "debug"},
+			{Path: "Password", Old: cfgx.Redacted, New: cfgx.Redacted},
+		}
+		if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+			t.Errorf("changes: wanted %v, got %v", want, changes)
+		}
+		if len(got) != len(changes) {
+			t.Errorf("callback: wanted %v, got %v", changes, got)
+		}
+		if r.Current().Level != "debug" {
+			t.Errorf("Level: wanted debug, got %s", r.Current().Level)
+		}
+		if first.Level != "info" {
+			t.Errorf("previous config was modified: %+v", first)
+		}
+	})
+
+	t.Run("CallbackUsesReloader", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{}
+		r := newReloader(t, fsys)
+
+		var current *reloadConfig
+		r.OnChange(func(prev, next *reloadConfig, changes []cfgx.Change) {
+			current = r.Current()
+			r.OnChange(func(prev, next *reloadConfig, changes []cfgx.Change) {})
+			if _, err := r.Reload(); err != nil {
+				t.Errorf("nested reload: %v", err)
+			}
+		})
+
+		fsys["level"] = &fstest.MapFile{Data: []byte("debug")}
+
+		done := make(chan error)
+		go func() {
+			_, err := r.Reload()
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("deadlock calling the Reloader from a callback")
+		}
+		if current == nil || current.Level != "debug" {
+			t.Errorf("callback: wanted the new config from Current, got %+v", current)
+		}
+	})
+
+	t.Run("NoChanges", func(t *testing.T) {
+		t.Parallel()
+		r := newReloader(t, fstest.MapFS{})
+		first := r.Current()
+
+		changes, err := r.Reload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 0 || r.Current() != first {
+			t.Errorf("wanted no changes, got %v", changes)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{}
+		r := newReloader(t, fsys)
+
+		fsys["level"] = &fstest.MapFile{Data: []byte("verbose")}
+
+		var valErr *cfgx.ValidationError
+		if _, err := r.Reload(); !errors.As(err, &valErr) {
+			t.Fatalf("wanted ValidationError, got %v", err)
+		}
+		if r.Current().Level != "info" {
+			t.Errorf("Level: wanted info kept, got %s", r.Current().Level)
+		}
+	})
+
+	t.Run("NotReloadable", func(t *testing.T) {
+		t.Parallel()
+		fsys := fstest.MapFS{}
+		r := newReloader(t, fsys)
+
+		fsys["level"] = &fstest.MapFile{Data: []byte("warn")}
+		fsys["port"] = &fstest.MapFile{Data: []byte("9090")}
+
+		var valErr *cfgx.ValidationError
+		if _, err := r.Reload(); !errors.As(err, &valErr) || valErr.Field != "Port" {
+			t.Fatalf("wanted ValidationError for Port, got %v", err)
+		}
+		if got := r.Current(); got.Port != 8080 || got.Level != "info" {
+			t.Errorf("wanted config kept, got %+v", got)
+		}
+	})
+}
+
+func TestReloader_Watch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("level: info\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := cfgx.NewReloader(reloadConfig{}, cfgx.Options{
+		SkipFlags: true,
+		SkipEnv:   true,
+		Sources:   []cfgx.Source{cfgx.NewFileSource(path)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Interval = 10 * time.Millisecond
+
+	reloaded := make(chan []cfgx.Change, 1)
+	r.OnChange(func(_, _ *reloadConfig, changes []cfgx.Change) {
+		reloaded <- changes
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Watch(ctx) }()
+
+	if err := os.WriteFile(path, []byte("level: warn\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changes := <-reloaded:
+		if len(changes) != 1 || changes[0].Path != "Level" || changes[0].New != "warn" {
+			t.Errorf("wanted Level changed to warn, got %v", changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch: wanted context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package cfgx
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ResolveFunc returns the value a reference points to, e.g. the
+// contents of the file for "file:/run/secrets/db". It is passed
+// the reference without the scheme.
+type ResolveFunc func(ref string) (string, error)
+
+// builtinResolvers are available without registering them.
+var builtinResolvers = map[string]ResolveFunc{
+	"file":    resolveFile,
+	encPrefix: noDecrypter,
+}
+
+// resolveFile reads the file, trimmed like a Docker secret.
+func resolveFile(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return readTrimmed(file, name)
+}
+
+// resolveEnv looks up the variable with lookupEnv.
+func resolveEnv(lookupEnv func(string) (string, bool)) ResolveFunc {
+	return func(name string) (string, error) {
+		val, ok := lookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return val, nil
+	}
+}
+
+// pendingValue is a raw value with references, kept by
+// [ConfigField.Set] or [ConfigField.SetItems].
+type pendingValue struct {
+	raw   []string
+	items bool
+}
+
+// resolver resolves references and ${} interpolation in the
+// raw values after all sources have run.
+type resolver struct {
+	schemes   map[string]ResolveFunc
+	fields    map[string]ConfigField
+	lookupEnv func(string) (string, bool)
+	// stack is the fields being resolved, to detect cycles.
+	stack []string
+}
+
+func newResolver(custom map[string]ResolveFunc, fields map[string]ConfigField, env map[string]string) *resolver {
+	lookup := lookupEnv(env)
+
+	schemes := maps.Clone(builtinResolvers)
+	schemes["env"] = resolveEnv(lookup)
+	maps.Copy(schemes, custom)
+	return &resolver{schemes: schemes, fields: fields, lookupEnv: lookup}
+}
+
+// needs reports whether the raw value is encrypted, or with refs
+// whether it has a registered scheme or ${} interpolation. A file:
+// reference must be an absolute path, so that values such as the
+// SQLite DSNs "file:app.db?cache=shared" and "file:///data/app.db"
+// are kept as is.
+func (r *resolver) needs(raw string, refs bool) bool {
+	scheme, ref, ok := strings.Cut(raw, ":")
+	_, registered := r.schemes[scheme]
+	switch {
+	case ok && scheme == encPrefix:
+		return registered
+	case !refs:
+		return false
+	case strings.Contains(raw, "${"):
+		return true
+	case ok && scheme == "file":
+		return filepath.IsAbs(ref) && !strings.HasPrefix(ref, "//")
+	}
+	return ok && registered
+}
+
+// hasReference reports whether the raw value will be resolved.
+func (f ConfigField) hasReference(raw string) bool {
+	return f.state != nil && f.state.resolver != nil && f.state.resolver.needs(raw, f.state.refs)
+}
+
+// deferred keeps the raw values if they have references, and clears
+// the pending value when a source sets a plain value instead.
+func (f ConfigField) deferred(raw []string, items bool) bool {
+	if f.state == nil {
+		return false
+	}
+	if !slices.ContainsFunc(raw, f.hasReference) {
+		f.state.pending = nil
+		return false
+	}
+
+	f.state.pending = &pendingValue{raw: raw, items: items}
+	f.markSet()
+	return true
+}
+
+// resolveAll resolves and sets every field with a pending value.
+func (r *resolver) resolveAll() []*fieldError {
+	var errs []*fieldError
+
+	for _, path := range slices.Sorted(maps.Keys(r.fields)) {
+		field := r.fields[path]
+		if err := r.resolveField(field); err != nil {
+			// Errors from setting the value already have the path
+			var fieldErr *fieldError
+			if !errors.As(err, &fieldErr) || fieldErr.path != path {
+				fieldErr = &fieldError{path: path, err: err}
+			}
+			errs = append(errs, fieldErr)
+		}
+	}
+
+	return errs
+}
+
+// resolveField resolves the pending value of the field and sets it.
+func (r *resolver) resolveField(field ConfigField) error {
+	if slices.Contains(r.stack, field.Path) {
+		return fmt.Errorf("reference cycle %s -> %s", strings.Join(r.stack, " -> "), field.Path)
+	}
+
+	pending := field.state.pending
+	if pending == nil {
+		return nil
+	}
+	// Cleared first so a failed field is only reported once
+	field.state.pending = nil
+
+	r.stack = append(r.stack, field.Path)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	resolved := make([]string, len(pending.raw))
+	for i, raw := range pending.raw {
+		val, err := r.resolve(raw, field.state.refs)
+		if err != nil {
+			field.state.failed = true
+			return err
+		}
+		resolved[i] = val
+	}
+
+	if pending.items {
+		return field.setItems(resolved)
+	}
+	return field.set(resolved[0])
+}
+
+// resolve interpolates the raw value and then resolves its scheme.
+// Without refs only encrypted values are resolved. Resolved values
+// are not resolved again.
+func (r *resolver) resolve(raw string, refs bool) (string, error) {
+	if !r.needs(raw, refs) {
+		return raw, nil
+	}
+
+	val := raw
+	if refs {
+		var err error
+		if val, err = r.interpolate(raw); err != nil {
+			return "", err
+		}
+		if !r.needs(val, refs) {
+			return val, nil
+		}
+	}
+
+	scheme, ref, ok := strings.Cut(val, ":")
+	fn, registered := r.schemes[scheme]
+	if !ok || !registered {
+		return val, nil
+	}
+
+	resolved, err := fn(ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s reference: %w", scheme, err)
+	}
+	return resolved, nil
+}
+
+// interpolate replaces ${Field.Path} with the value of the field and
+// ${NAME} with the environment variable. $${ is a literal ${.
+func (r *resolver) interpolate(raw string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case strings.HasPrefix(raw[i:], "$${"):
+			b.WriteString("${")
+			i += 2
+		case strings.HasPrefix(raw[i:], "${"):
+			end := strings.IndexByte(raw[i:], '}')
+			if end < 0 {
+				return "", errors.New("unclosed ${")
+			}
+			val, err := r.lookup(raw[i+2 : i+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+			i += end
+		default:
+			b.WriteByte(raw[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// lookup returns the value of the field at the path, resolving it
+// first, or else the environment variable. A secret field can only be
+// interpolated into another secret, so its value is never shown.
+func (r *resolver) lookup(name string) (string, error) {
+	if field, ok := r.fields[name]; ok {
+		if field.isSecret() && !r.fields[r.stack[len(r.stack)-1]].isSecret() {
+			return "", fmt.Errorf("${%s} is a secret, so the field must be tagged secret too", name)
+		}
+		if err := r.resolveField(field); err != nil {
+			return "", err
+		}
+		return strings.Join(fieldStrings(field), field.separator()), nil
+	}
+
+	if val, ok := r.lookupEnv(name); ok {
+		return val, nil
+	}
+
+	return "", fmt.Errorf("${%s} is not a field or environment variable", name)
+}
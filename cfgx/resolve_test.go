@@ -0,0 +1,226 @@
+package cfgx_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("RESOLVE_DB_PASSWORD", "file:"+secret)
+	os.Setenv("RESOLVE_DB_USER", "env:RESOLVE_TEST_USER")
+	os.Setenv("RESOLVE_TEST_USER", "app")
+	os.Setenv("RESOLVE_TEST_REGION", "eu")
+	cleanupEnv(t, "RESOLVE_DB_PASSWORD", "RESOLVE_DB_USER", "RESOLVE_TEST_USER", "RESOLVE_TEST_REGION")
+
+	var cfg struct {
+		DB struct {
+			User     string
+			Password string `secret:"true"`
+			Host     string `default:"localhost"`
+			Port     int    `default:"5432"`
+		}
+		DSN     string `default:"postgres://${DB.User}@${DB.Host}:${DB.Port}/app"`
+		Bucket  string `default:"assets-${RESOLVE_TEST_REGION}"`
+		Port    int    `default:"${DB.Port}"`
+		Literal string `default:"$${NOT_RESOLVED}"`
+		Raw     string `default:"${KEPT}" resolve:"false"`
+		Vault   string `default:"vault:secret/api"`
+	}
+
+	var prov cfgx.Provenance
+	err := cfgx.Parse(&cfg, cfgx.Options{
+		EnvPrefix:  "RESOLVE",
+		Args:       []string{"--db-host", "db.internal"},
+		Provenance: &prov,
+		Resolve:    true,
+		Resolvers: map[string]cfgx.ResolveFunc{
+			"vault": func(ref string) (string, error) { return "from-" + ref, nil },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DB.User":     "app",
+		"DB.Password": "s3cret",
+		"DSN":         "postgres://app@db.internal:5432/app",
+		"Bucket":      "assets-eu",
+		"Literal":     "${NOT_RESOLVED}",
+		"Raw":         "${KEPT}",
+		"Vault":       "from-secret/api",
+	}
+	got := map[string]string{
+		"DB.User":     cfg.DB.User,
+		"DB.Password": cfg.DB.Password,
+		"DSN":         cfg.DSN,
+		"Bucket":      cfg.Bucket,
+		"Literal":     cfg.Literal,
+		"Raw":         cfg.Raw,
+		"Vault":       cfg.Vault,
+	}
+	for path, w := range want {
+		if got[path] != w {
+			t.Errorf("%s: wanted %q, got %q", path, w, got[path])
+		}
+	}
+	if cfg.Port != 5432 {
+		t.Errorf("Port: wanted 5432, got %d", cfg.Port)
+	}
+
+	if f, _ := prov.Field("DB.Password"); f.Source == nil || f.Source.Source != "env" || f.Value != cfgx.Redacted {
+		t.Errorf("DB.Password: wanted redacted from env, got %+v", f)
+	}
+}
+
+func TestResolve_Override(t *testing.T) {
+	os.Setenv("OVERRIDE_TOKEN", "env:OVERRIDE_UNSET")
+	cleanupEnv(t, "OVERRIDE_TOKEN")
+
+	var cfg struct {
+		Token string
+	}
+
+	// A plain value from a higher priority source replaces the reference
+	err := cfgx.Parse(&cfg, cfgx.Options{EnvPrefix: "OVERRIDE", Args: []string{"--token", "plain"}, Resolve: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Token != "plain" {
+		t.Errorf("Token: wanted plain, got %q", cfg.Token)
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		cfg   any
+		field string
+		msg   string
+	}{
+		"Cycle": {
+			cfg: &struct {
+				A string `default:"${B}"`
+				B string `default:"x${A}"`
+			}{},
+			field: "A",
+			msg:   "reference cycle A -> B -> A",
+		},
+		"Undefined": {
+			cfg: &struct {
+				A string `default:"${RESOLVE_UNDEFINED_VAR}"`
+			}{},
+			field: "A",
+			msg:   "${RESOLVE_UNDEFINED_VAR} is not a field or environment variable",
+		},
+		"MissingFile": {
+			cfg: &struct {
+				A string `default:"file:/does/not/exist"`
+			}{},
+			field: "A",
+			msg:   "resolve file reference",
+		},
+		"SecretInPlainField": {
+			cfg: &struct {
+				Password string `default:"hunter2" secret:"true"`
+				DSN      string `default:"pg://app:${Password}@localhost"`
+				Secret   string `default:"pg://app:${Password}@localhost" secret:"true"`
+			}{},
+			field: "DSN",
+			msg:   "${Password} is a secret, so the field must be tagged secret too",
+		},
+		"InvalidResolved": {
+			cfg: &struct {
+				Host string `default:"localhost"`
+				Port int    `default:"${Host}"`
+			}{},
+			field: "Port",
+			msg:   "invalid syntax",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := cfgx.Parse(tt.cfg, cfgx.Options{SkipFlags: true, SkipEnv: true, Resolve: true})
+
+			var srcErr *cfgx.SourceError
+			if !errors.As(err, &srcErr) {
+				t.Fatalf("expected SourceError, got %v", err)
+			}
+			if srcErr.Source != "default" || srcErr.Field != tt.field {
+				t.Errorf("expected default and %s, got %s and %s", tt.field, srcErr.Source, srcErr.Field)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("expected %q in %v", tt.msg, err)
+			}
+
+			var multi *cfgx.MultiError
+			if errors.As(err, &multi) && len(multi.Errors) != 1 {
+				t.Errorf("expected 1 error, got %v", multi.Errors)
+			}
+		})
+	}
+}
+
+func TestResolve_OptIn(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		Path   string `default:"file:/etc/app"`
+		Pass   string `default:"a${b" secret:"true"`
+		Region string `default:"eu"`
+		Bucket string `default:"assets-${Region}" resolve:"true"`
+	}
+
+	// Only the tagged field is resolved
+	err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path != "file:/etc/app" || cfg.Pass != "a${b" {
+		t.Errorf("wanted the values kept, got %q and %q", cfg.Path, cfg.Pass)
+	}
+	if cfg.Bucket != "assets-eu" {
+		t.Errorf("Bucket: wanted assets-eu, got %q", cfg.Bucket)
+	}
+}
+
+func TestResolve_Literals(t *testing.T) {
+	t.Parallel()
+
+	// Values that look like references but were always valid as is
+	env := map[string]string{
+		"DSN":      "file:app.db?cache=shared",
+		"URL_DSN":  "file:///data/app.db?mode=ro",
+		"PASSWORD": "a${b",
+	}
+
+	for _, resolve := range []bool{false, true} {
+		var cfg struct {
+			DSN      string
+			URLDSN   string `env:"URL_DSN"`
+			Password string `secret:"true" resolve:"false"`
+		}
+		err := cfgx.Parse(&cfg, cfgx.Options{Env: env, Args: []string{}, Resolve: resolve})
+		if err != nil {
+			t.Fatalf("resolve %t: %v", resolve, err)
+		}
+		if cfg.DSN != env["DSN"] || cfg.URLDSN != env["URL_DSN"] || cfg.Password != env["PASSWORD"] {
+			t.Errorf("resolve %t: wanted the values as is, got %+v", resolve, cfg)
+		}
+	}
+}
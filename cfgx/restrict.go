@@ -0,0 +1,39 @@
+package cfgx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	tagSources = "sources" // Comma separated names of the sources that may set the field
+)
+
+// Sources that may always set a field, since their values are in the code.
+var codeSources = []string{"struct", "build", "default"}
+
+// allowedSources returns the names in the sources tag, or nil if any source may set the field.
+func (f ConfigField) allowedSources() []string {
+	return splitList(f.Tag.Get(tagSources), defaultSeparator)
+}
+
+// allowsSource reports whether the source may set the field. A profile's
+// source, e.g. "file.prod", is allowed by the name of the source it wraps.
+func (f ConfigField) allowsSource(name string) bool {
+	allowed := f.allowedSources()
+	if len(allowed) == 0 {
+		return true
+	}
+
+	base, _, _ := strings.Cut(name, ".")
+	return slices.Contains(codeSources, base) || slices.Contains(allowed, name) || slices.Contains(allowed, base)
+}
+
+// disallowedError is the error for a source that set a field it may not.
+func (f ConfigField) disallowedError(source string) error {
+	return &ValidationError{
+		Field:  f.Path,
+		Reason: fmt.Sprintf("cannot be set by %s, only by %s", source, strings.Join(f.allowedSources(), ", ")),
+	}
+}
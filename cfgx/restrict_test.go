@@ -0,0 +1,96 @@
+package cfgx_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type restrictConfig struct {
+	Host     string `default:"localhost"`
+	Password string `secret:"true" sources:"secret,env"`
+	Token    string `sources:"custom" default:"dev-token"`
+}
+
+// customSource is a named custom source that sets the token.
+type customSource struct{ name string }
+
+func (s customSource) Name() string  { return s.name }
+func (s customSource) Priority() int { return cfgx.PriorityFile }
+func (s customSource) Process(fields map[string]cfgx.ConfigField) error {
+	fields["Token"].Value.SetString("from-" + s.name)
+	return nil
+}
+
+func TestSourcesTag(t *testing.T) {
+	t.Run("Allowed", func(t *testing.T) {
+		os.Setenv("RESTRICT_PASSWORD", "s3cret")
+		cleanupEnv(t, "RESTRICT_PASSWORD")
+
+		var cfg restrictConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix: "RESTRICT",
+			Args:      []string{"--host", "example.com"},
+			Sources:   []cfgx.Source{customSource{name: "custom"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Host != "example.com" || cfg.Password != "s3cret" || cfg.Token != "from-custom" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("DefaultAllowed", func(t *testing.T) {
+		var cfg restrictConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipEnv: true, Args: []string{}})
+
+		// Only the required Password is missing
+		var valErr *cfgx.ValidationError
+		if !errors.As(err, &valErr) || valErr.Field != "Password" || strings.Contains(err.Error(), "cannot be set") {
+			t.Fatalf("expected only a required error for Password, got %v", err)
+		}
+		if cfg.Token != "dev-token" {
+			t.Errorf("Token: wanted dev-token, got %s", cfg.Token)
+		}
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		var cfg restrictConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			SkipEnv: true,
+			Args:    []string{"--password", "hunter2"},
+			Output:  io.Discard,
+		})
+
+		var valErr *cfgx.ValidationError
+		if !errors.As(err, &valErr) || valErr.Field != "Password" {
+			t.Fatalf("expected a validation error for Password, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "cannot be set by flag, only by secret, env") {
+			t.Errorf("unexpected message: %v", err)
+		}
+		if strings.Contains(err.Error(), "hunter2") {
+			t.Errorf("secret value in error: %v", err)
+		}
+	})
+
+	t.Run("CustomSource", func(t *testing.T) {
+		os.Setenv("RESTRICT_PASSWORD", "s3cret")
+		cleanupEnv(t, "RESTRICT_PASSWORD")
+
+		var cfg restrictConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			EnvPrefix: "RESTRICT",
+			SkipFlags: true,
+			Sources:   []cfgx.Source{customSource{name: "other"}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "validation error for field 'Token': cannot be set by other, only by custom") {
+			t.Errorf("expected a Token error, got %v", err)
+		}
+	})
+}
@@ -1,24 +1,78 @@
 package cfgx
 
 import (
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"reflect"
-	"strconv"
+	"runtime/debug"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/erlorenz/go-toolbox/casing"
 )
 
 const (
-	dockerPath     = "/run/secrets"
-	maxSecretSize  = 1 << 20 // 1MB - max size for secret files
+	dockerPath    = "/run/secrets"
+	maxSecretSize = 1 << 20 // 1MB - max size for secret files
 )
 
+// Build info ================================================================
+type buildSource struct {
+	priority int
+}
+
+func (s *buildSource) Priority() int {
+	return s.priority
+}
+
+func (s *buildSource) Name() string {
+	return "build"
+}
+
+// Process sets a top level Version field from the build info.
+func (s *buildSource) Process(fields map[string]ConfigField) error {
+	version, ok := fields["Version"]
+	if !ok || version.Kind != reflect.String {
+		return nil
+	}
+
+	v := "(devel)"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		v = cmp.Or(bi.Main.Version, v)
+	}
+	version.Value.SetString(v)
+	version.markSet()
+	return nil
+}
+
+// Struct ====================================================================
+type structSource struct {
+	priority int
+}
+
+func (s *structSource) Priority() int {
+	return s.priority
+}
+
+func (s *structSource) Name() string {
+	return "struct"
+}
+
+// Process marks the fields already set in the struct as provided.
+func (s *structSource) Process(fields map[string]ConfigField) error {
+	for _, field := range fields {
+		if !field.Value.IsZero() {
+			field.markSet()
+		}
+	}
+	return nil
+}
+
 // Default ===================================================================
 type defaultSource struct {
 	priority int
@@ -28,6 +82,10 @@ func (s *defaultSource) Priority() int {
 	return s.priority
 }
 
+func (s *defaultSource) Name() string {
+	return "default"
+}
+
 func (s *defaultSource) Process(fields map[string]ConfigField) error {
 	var allErrs []error
 
@@ -37,55 +95,8 @@ func (s *defaultSource) Process(fields map[string]ConfigField) error {
 			continue
 		}
 
-		// Handle time.Duration specially (it's an int64 alias)
-		if field.Value.Type() == reflect.TypeOf(time.Duration(0)) {
-			d, err := time.ParseDuration(defVal)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot parse duration %s: %w", field.Path, err))
-				continue
-			}
-			field.Value.Set(reflect.ValueOf(d))
-			continue
-		}
-
-		switch field.Kind {
-		// String
-		case reflect.String:
-			field.Value.SetString(defVal)
-		// Int
-		case reflect.Int:
-			intVal, err := strconv.ParseInt(defVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetInt(intVal)
-		case reflect.Int64:
-			intVal, err := strconv.ParseInt(defVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetInt(intVal)
-		case reflect.Uint:
-			uintVal, err := strconv.ParseUint(defVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetUint(uintVal)
-		case reflect.Float64:
-			floatVal, err := strconv.ParseFloat(defVal, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetFloat(floatVal)
-		case reflect.Bool:
-			boolVal, _ := strconv.ParseBool(defVal)
-			field.Value.SetBool(boolVal)
-		default:
-			allErrs = append(allErrs, fmt.Errorf("cannot set %s: unimplemented kind %s", field.Path, field.Kind))
+		if err := field.Set(defVal); err != nil {
+			allErrs = append(allErrs, err)
 		}
 	}
 	if len(allErrs) > 0 {
@@ -98,86 +109,43 @@ func (s *defaultSource) Process(fields map[string]ConfigField) error {
 type envSource struct {
 	priority int
 	prefix   string
+	strict   bool
+	ignore   []string // Prefixes that are not unknown in strict mode
+	env      map[string]string
+	skipped  map[string]ConfigField // Already set, but not unknown in strict mode
 }
 
 func (s *envSource) Priority() int {
 	return s.priority
 }
 
+func (s *envSource) Name() string {
+	return "env"
+}
+
 func (s *envSource) Process(fields map[string]ConfigField) error {
 	var allErrs []error
 
-	for _, field := range fields {
-
-		envName := casing.ToScreamingSnake(field.Path)
-		// Add prefix
-		if s.prefix != "" {
-			envName = s.prefix + "_" + envName
-		}
+	toName := func(path string) string { return envPathName(path, s.prefix) }
 
-		// Overwrite with tag
-		tagVal, ok := field.Tag.Lookup(tagEnv)
-		if ok {
-			envName = tagVal
+	for _, field := range fields {
+		// Get value from env, or an alias
+		envVal, ok, err := field.lookupNames(nameEnv, envName(field, s.prefix), toName, lookupEnv(s.env))
+		if err != nil {
+			allErrs = append(allErrs, err)
+			continue
 		}
-
-		// Get value from env
-		envVal, ok := os.LookupEnv(envName)
 		if !ok {
 			continue
 		}
 
-		// Handle time.Duration specially (it's an int64 alias)
-		if field.Value.Type() == reflect.TypeOf(time.Duration(0)) {
-			d, err := time.ParseDuration(envVal)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot parse duration %s: %w", field.Path, err))
-				continue
-			}
-			field.Value.Set(reflect.ValueOf(d))
-			continue
+		if err := field.Set(envVal); err != nil {
+			allErrs = append(allErrs, err)
 		}
+	}
 
-		switch field.Kind {
-		// String
-		case reflect.String:
-			field.Value.SetString(envVal)
-		// Int
-		case reflect.Int:
-			intVal, err := strconv.ParseInt(envVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetInt(intVal)
-		case reflect.Int64:
-			intVal, err := strconv.ParseInt(envVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetInt(intVal)
-		case reflect.Uint:
-			uintVal, err := strconv.ParseUint(envVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetUint(uintVal)
-		case reflect.Float64:
-			floatVal, err := strconv.ParseFloat(envVal, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetFloat(floatVal)
-		// Bool
-		case reflect.Bool:
-			boolVal, _ := strconv.ParseBool(envVal)
-			field.Value.SetBool(boolVal)
-		default:
-			allErrs = append(allErrs, fmt.Errorf("cannot set %s: unimplemented kind %s", field.Path, field.Kind))
-		}
+	if s.strict && s.prefix != "" {
+		allErrs = append(allErrs, s.unknown(fields)...)
 	}
 
 	if len(allErrs) > 0 {
@@ -186,108 +154,396 @@ func (s *envSource) Process(fields map[string]ConfigField) error {
 	return nil
 }
 
+// unknown returns an error for each variable with the prefix
+// that matches no field, to catch typos.
+func (s *envSource) unknown(fields map[string]ConfigField) []error {
+	toName := func(path string) string { return envPathName(path, s.prefix) }
+
+	known := map[string]bool{}
+	for _, field := range allFields(fields, s.skipped) {
+		known[envName(field, s.prefix)] = true
+		for _, alt := range field.altNames(nameEnv, toName) {
+			known[alt.name] = true
+		}
+	}
+
+	var allErrs []error
+	for _, env := range slices.Sorted(slices.Values(environ(s.env))) {
+		name, _, _ := strings.Cut(env, "=")
+		ignored := slices.ContainsFunc(s.ignore, func(prefix string) bool {
+			return strings.HasPrefix(name, prefix+"_")
+		})
+		if strings.HasPrefix(name, s.prefix+"_") && !known[name] && !ignored {
+			allErrs = append(allErrs, fmt.Errorf("unknown environment variable %s", name))
+		}
+	}
+	return allErrs
+}
+
+// allFields returns the fields with the skipped fields, which are
+// known names but not set by the source.
+func allFields(fields, skipped map[string]ConfigField) map[string]ConfigField {
+	all := maps.Clone(fields)
+	maps.Copy(all, skipped)
+	return all
+}
+
+// lookupEnv looks up variables in env, or the process environment if nil.
+func lookupEnv(env map[string]string) func(name string) (string, bool) {
+	if env == nil {
+		return os.LookupEnv
+	}
+	return func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+}
+
+// environ returns env as "name=value", or the process environment if nil.
+func environ(env map[string]string) []string {
+	if env == nil {
+		return os.Environ()
+	}
+	vars := make([]string, 0, len(env))
+	for name, val := range env {
+		vars = append(vars, name+"="+val)
+	}
+	return vars
+}
+
+// envName is the SCREAMING_SNAKE path with the prefix, or the "env" tag.
+func envName(field ConfigField, prefix string) string {
+	// Overwrite with tag
+	if tagVal, ok := field.Tag.Lookup(tagEnv); ok {
+		return tagVal
+	}
+
+	return envPathName(field.Path, prefix)
+}
+
+// envPathName is the SCREAMING_SNAKE path with the prefix.
+func envPathName(path, prefix string) string {
+	name := casing.ToScreamingSnake(path)
+	// Add prefix
+	if prefix != "" {
+		name = prefix + "_" + name
+	}
+	return name
+}
+
 // Flag ===================================================================
 type flagSource struct {
 	priority int
 	opts     Options
+	// usage prints the help for -h and --help.
+	usage func()
+	// rest is the positional args after the flags.
+	rest []string
+	// stop ends GNU parsing at the first positional arg.
+	stop bool
+	// version adds the --version flag, and showVersion is
+	// true when it was provided.
+	version, showVersion bool
+	// skipped are the fields already set, whose flags are
+	// parsed but not set.
+	skipped map[string]ConfigField
 }
 
 func (s *flagSource) Priority() int {
 	return s.priority
 }
+
+func (s *flagSource) Name() string {
+	return "flag"
+}
+
 func (s *flagSource) Process(fields map[string]ConfigField) error {
 	var allErrs []error
 
-	flags := flag.NewFlagSet(s.opts.ProgramName, s.opts.ErrorHandling)
+	// Temporary map of the raw values collected for each field,
+	// except the one that collects the positional args
+	flagValues := map[string]*fieldFlag{}
+	var aliases []*aliasFlag
+	var argsField *ConfigField
+
+	for path, field := range allFields(fields, s.skipped) {
+		if field.Tag.Get(tagArgs) == "true" {
+			if _, ok := fields[path]; ok {
+				argsField = &field
+			}
+			continue
+		}
+		flagValues[path] = &fieldFlag{field: field}
+
+		for _, alt := range field.altNames(nameFlag, casing.ToKebab) {
+			aliases = append(aliases, &aliasFlag{altName: alt, value: &fieldFlag{field: field}})
+		}
+	}
+
+	// Add --version for the build info, unless a field has the name
+	var version *fieldFlag
+	if s.version && !usesFlagName(fields, versionFlag) {
+		version = newVersionFlag()
+		flagValues[versionFlag] = version
+	}
 
-	// Temporary flag map of pointers to values
-	flagValues := map[string]any{}
+	var err error
+	if s.opts.GNUFlags {
+		s.rest, err = s.parseGNU(flagValues, aliases)
+	} else {
+		s.rest, err = s.parseGo(flagValues, aliases)
+	}
+	if err != nil {
+		return err
+	}
 
-	// Load the flagValues map with the flag values
-	for path, field := range fields {
-		flagName := casing.ToKebab(field.Path)
-		shortFlagName := field.Tag.Get(tagShort)
+	if version != nil {
+		delete(flagValues, versionFlag)
+		s.showVersion = hasVersionFlag(version)
+	}
 
-		// Overwrite with tag
-		if tagVal, ok := field.Tag.Lookup(tagFlag); ok {
-			flagName = tagVal
+	// Merge the aliases that were provided into the flags
+	for _, alias := range aliases {
+		if alias.value.raw == nil {
+			continue
+		}
+		if err := alias.merge(flagValues[alias.value.field.Path]); err != nil {
+			allErrs = append(allErrs, err)
 		}
+	}
 
-		// Handle time.Duration specially
-		if field.Value.Type() == reflect.TypeOf(time.Duration(0)) {
-			flagValues[path] = flags.Duration(flagName, 0, field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.Duration(shortFlagName, 0, field.Description)
-			}
+	// Ignore the flags of the skipped fields
+	for path := range s.skipped {
+		delete(flagValues, path)
+	}
+
+	// Now set the values of the flags that were provided
+	for _, value := range flagValues {
+		if value.raw == nil {
 			continue
 		}
 
-		switch field.Kind {
-		case reflect.String:
-			flagValues[path] = flags.String(flagName, "", field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.String(shortFlagName, "", field.Description)
-			}
-		case reflect.Int:
-			flagValues[path] = flags.Int(flagName, 0, field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.Int(shortFlagName, 0, field.Description)
-			}
-		case reflect.Int64:
-			flagValues[path] = flags.Int64(flagName, 0, field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.Int64(shortFlagName, 0, field.Description)
-			}
-		case reflect.Uint:
-			flagValues[path] = flags.Uint(flagName, 0, field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.Uint(shortFlagName, 0, field.Description)
-			}
-		case reflect.Float64:
-			flagValues[path] = flags.Float64(flagName, 0, field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.Float64(shortFlagName, 0, field.Description)
+		if err := value.field.SetItems(value.raw); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if argsField != nil && len(s.rest) > 0 {
+		if err := argsField.SetItems(s.rest); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	if len(allErrs) > 0 {
+		return &MultiError{allErrs}
+	}
+	return nil
+}
+
+// parseGo parses the args with the flag package and returns the positional args.
+func (s *flagSource) parseGo(flagValues map[string]*fieldFlag, aliases []*aliasFlag) ([]string, error) {
+	// Parse always continues so Parse can handle the error
+	flags := flag.NewFlagSet(s.opts.ProgramName, flag.ContinueOnError)
+	flags.SetOutput(s.opts.Output)
+	if s.usage != nil {
+		flags.Usage = s.usage
+	}
+
+	// Register a flag (and short flag) for each field
+	for _, value := range flagValues {
+		flagName, shortFlagName := flagNames(value.field)
+
+		flags.Var(value, flagName, value.field.Description)
+		if shortFlagName != "" {
+			flags.Var(value, shortFlagName, value.field.Description)
+		}
+	}
+	for _, alias := range aliases {
+		flags.Var(alias.value, alias.name, alias.value.field.Description)
+	}
+
+	// Skip unknown flags unless strict
+	args := s.opts.Args
+	if !s.opts.Strict {
+		args = knownFlags(flags, args)
+	}
+
+	// Parse flags, reporting an invalid value with the field path
+	if err := flags.Parse(args); err != nil {
+		for _, value := range flagValues {
+			if value.err != nil {
+				return nil, value.err
 			}
-		case reflect.Bool:
-			flagValues[path] = flags.Bool(flagName, false, field.Description)
-			if shortFlagName != "" {
-				flagValues[path+"-short"] = flags.Bool(shortFlagName, false, field.Description)
+		}
+		for _, alias := range aliases {
+			if alias.value.err != nil {
+				return nil, alias.value.err
 			}
 		}
+		return nil, fmt.Errorf("failed parsing flags: %w", err)
+	}
+
+	return flags.Args(), nil
+}
 
+// parseGNU parses the args with a [gnuParser] and returns the positional
+// args. Like the flag package it writes the error and the usage.
+func (s *flagSource) parseGNU(flagValues map[string]*fieldFlag, aliases []*aliasFlag) ([]string, error) {
+	p := newGNUParser(flagValues, aliases, s.opts.Args)
+	p.strict = s.opts.Strict
+	p.stop = s.stop
+
+	rest, err := p.parse()
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(s.opts.Output, err)
+		}
+		if s.usage != nil {
+			s.usage()
+		}
+		return nil, err
 	}
 
-	// Parse flags
-	if err := flags.Parse(s.opts.Args); err != nil {
-		return fmt.Errorf("failed parsing flags: %w", err)
+	return rest, nil
+}
+
+// flagNames returns the kebab-case path or the "flag" tag, and the "short" tag.
+func flagNames(field ConfigField) (name, short string) {
+	name = casing.ToKebab(field.Path)
+
+	// Overwrite with tag
+	if tagVal, ok := field.Tag.Lookup(tagFlag); ok {
+		name = tagVal
 	}
 
-	// Now set the values to the fields
-	for path, flagValPtr := range flagValues {
-		// Skip the default
-		flagVal := reflect.ValueOf(flagValPtr).Elem()
-		if flagVal.IsZero() {
-			continue
+	return name, field.Tag.Get(tagShort)
+}
+
+// knownFlags removes the flags that are not defined from the args. Their
+// value is only removed with "--name=value": the argument after one could
+// be positional, so it is kept and moved after the known flags, which are
+// still parsed. Like the flag package it stops at "--" or the first other
+// argument that is not a flag.
+func knownFlags(flags *flag.FlagSet, args []string) []string {
+	var known, kept []string
+
+	// rest puts the kept args before the positional args, after a "--"
+	// so the flag package doesn't stop at them and miss a "--"
+	rest := func(args []string) []string {
+		if len(kept) == 0 {
+			return append(known, args...)
 		}
+		if len(args) > 0 && args[0] == "--" {
+			args = args[1:]
+		}
+		return slices.Concat(known, []string{"--"}, kept, args)
+	}
 
-		// Make short use same field
-		path = strings.TrimSuffix(path, "-short")
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			return rest(args[i:])
+		}
 
-		field := fields[path]
-		field.Value.Set(flagVal)
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue := !hasValue && i+1 < len(args)
+
+		f := flags.Lookup(name)
+		switch {
+		case name == "h" || name == "help":
+			known = append(known, arg)
+		case f != nil:
+			known = append(known, arg)
+			if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); takesValue && !(ok && boolFlag.IsBoolFlag()) {
+				i++
+				known = append(known, args[i])
+			}
+		case takesValue && !strings.HasPrefix(args[i+1], "-"):
+			i++
+			kept = append(kept, args[i])
+		}
 	}
 
-	if len(allErrs) > 0 {
-		return &MultiError{allErrs}
+	return rest(nil)
+}
+
+// fieldFlag is a [flag.Value] that collects the raw values for a field.
+// Repeated flags append for slices and maps, and the last one wins otherwise.
+type fieldFlag struct {
+	field ConfigField
+	raw   []string
+	err   error // Invalid value, with the field path
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil || f.raw == nil {
+		return ""
 	}
+	return strings.Join(f.raw, defaultSeparator)
+}
+
+func (f *fieldFlag) Set(s string) error {
+	if !f.field.isList() {
+		// References are validated once resolved
+		if f.field.hasReference(s) {
+			f.raw = []string{s}
+			return nil
+		}
+		// Validate now so the flag package reports it, unless it is a
+		// secret because the flag package prints the value.
+		if f.field.isSecret() {
+			f.raw = []string{s}
+			return nil
+		}
+		if _, err := parseValue(f.field.Value.Type(), s, f.field.decoders); err != nil {
+			f.err = f.field.setError(err)
+			return err
+		}
+		f.raw = []string{s}
+		return nil
+	}
+	// An empty value is an empty list, which is provided and clears it
+	if f.raw == nil {
+		f.raw = []string{}
+	}
+	f.raw = append(f.raw, splitList(s, f.field.separator())...)
 	return nil
 }
 
+// aliasFlag collects the values of an alias or deprecated flag name.
+type aliasFlag struct {
+	altName
+	value *fieldFlag
+}
+
+// merge reports a deprecated name, and moves the values into the
+// field's flag unless they were set to different values.
+func (a *aliasFlag) merge(f *fieldFlag) error {
+	field := a.value.field
+	name, _ := flagNames(field)
+
+	if a.deprecated {
+		field.deprecated(Deprecation{Field: field.Path, Kind: nameFlag, Name: a.name, Replacement: name})
+	}
+
+	if f.raw != nil && !slices.Equal(f.raw, a.value.raw) {
+		return &fieldError{field.Path, fmt.Errorf("%s and %s are both set to different values", a.name, name)}
+	}
+	f.raw = a.value.raw
+	return nil
+}
+
+// IsBoolFlag allows bool fields to be set with -name instead of -name=true.
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.field.Kind == reflect.Bool
+}
+
 // ====================================================================
 // Docker Secrets
 
 // DockerSecretsSource wraps a [FileContentSource].
-// It reads the docker secret file at “/run/secrets/<secret_name>“.
+// It reads the docker secret file at “/run/secrets/<secret_name>“,
+// or from the FS if it is set, e.g. an [fstest.MapFS] in tests.
 // It defaults to snake case based on the struct path.
 // Override the name with the tag "dsec".
 type DockerSecretsSource struct {
@@ -296,16 +552,45 @@ type DockerSecretsSource struct {
 }
 
 // Process opens an [os.Root] and calls the underlying [FileContentSource]'s
-// Process method with the [os.Root.FS].
+// Process method with the [os.Root.FS]. A missing secrets path is skipped.
 func (s *DockerSecretsSource) Process(structMap map[string]ConfigField) error {
+	if s.FS != nil {
+		return s.FileContentSource.Process(structMap)
+	}
+
 	root, err := os.OpenRoot(s.SecretsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("open docker path: %w", err)
 	}
 	defer root.Close()
 
-	s.FileContentSource.FS = root.FS()
-	return s.FileContentSource.Process(structMap)
+	// Keep FS unset, since the root is closed after
+	content := s.FileContentSource
+	content.FS = root.FS()
+	return content.Process(structMap)
+}
+
+// Fingerprint implements [Fingerprinter] with a hash of the secret files.
+func (s *DockerSecretsSource) Fingerprint() (string, error) {
+	if s.FS != nil {
+		return s.FileContentSource.Fingerprint()
+	}
+
+	root, err := os.OpenRoot(s.SecretsPath)
+	if err != nil {
+		return "", fmt.Errorf("open docker path: %w", err)
+	}
+	defer root.Close()
+
+	return fingerprintDir(root.FS())
+}
+
+// Name implements [NamedSource].
+func (s *DockerSecretsSource) Name() string {
+	return "secret"
 }
 
 // NewDockerSecretsSource sets a priority of PrioritySecrets (75), a tag of "dsec",
@@ -339,6 +624,28 @@ func (s *FileContentSource) Priority() int {
 	return s.PriorityLevel
 }
 
+// Name implements [NamedSource].
+func (s *FileContentSource) Name() string {
+	return "file-content"
+}
+
+// Fingerprint implements [Fingerprinter] with a hash of the files in FS.
+func (s *FileContentSource) Fingerprint() (string, error) {
+	if s.FS == nil {
+		return "", fmt.Errorf("fingerprint SourceFileContent: fs.FS cannot be nil")
+	}
+	return fingerprintDir(s.FS)
+}
+
+// fileName is the snake_case path or the value of the source's tag.
+func (s *FileContentSource) fileName(field ConfigField) string {
+	// override name
+	if tagVal, ok := field.Tag.Lookup(s.Tag); ok {
+		return tagVal
+	}
+	return casing.ToSnake(field.Path)
+}
+
 // Process implements [Source].
 func (s *FileContentSource) Process(structMap map[string]ConfigField) error {
 	if s.FS == nil {
@@ -347,86 +654,34 @@ func (s *FileContentSource) Process(structMap map[string]ConfigField) error {
 
 	var allErrs []error
 
-	for name, field := range structMap {
-
-		secretName := casing.ToSnake(name)
-
-		// override name
-		tagVal, ok := field.Tag.Lookup(s.Tag)
-		if ok {
-			secretName = tagVal
-		}
-
-		// skip if it doesn't exist
+	// Read a secret file, skipping it if it doesn't exist
+	read := func(secretName string) (string, bool) {
 		file, err := s.FS.Open(secretName)
 		if err != nil {
-			continue
+			return "", false
 		}
 		defer file.Close()
 
-		// Limit read size to prevent memory exhaustion
-		limitedReader := io.LimitReader(file, maxSecretSize+1)
-		b, err := io.ReadAll(limitedReader)
+		val, err := readTrimmed(file, secretName)
 		if err != nil {
-			allErrs = append(allErrs, fmt.Errorf("cannot read file %s: %w", secretName, err))
-			continue
+			allErrs = append(allErrs, err)
+			return "", false
 		}
-		if len(b) > maxSecretSize {
-			allErrs = append(allErrs, fmt.Errorf("file %s exceeds max size of %d bytes", secretName, maxSecretSize))
+		return val, true
+	}
+
+	for _, field := range structMap {
+		secretVal, ok, err := field.lookupNames(nameSecret, s.fileName(field), casing.ToSnake, read)
+		if err != nil {
+			allErrs = append(allErrs, err)
 			continue
 		}
-		secretVal := strings.TrimSpace(string(b))
-
-		// Handle time.Duration specially (it's an int64 alias)
-		if field.Value.Type() == reflect.TypeOf(time.Duration(0)) {
-			d, err := time.ParseDuration(secretVal)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot parse duration %s: %w", field.Path, err))
-				continue
-			}
-			field.Value.Set(reflect.ValueOf(d))
+		if !ok {
 			continue
 		}
 
-		switch field.Kind {
-		// String
-		case reflect.String:
-			field.Value.SetString(secretVal)
-		// Int
-		case reflect.Int:
-			intVal, err := strconv.ParseInt(secretVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetInt(intVal)
-		case reflect.Int64:
-			intVal, err := strconv.ParseInt(secretVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetInt(intVal)
-		case reflect.Uint:
-			uintVal, err := strconv.ParseUint(secretVal, 10, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetUint(uintVal)
-		case reflect.Float64:
-			floatVal, err := strconv.ParseFloat(secretVal, 64)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("cannot set %s: %w", field.Path, err))
-				break
-			}
-			field.Value.SetFloat(floatVal)
-		// Bool
-		case reflect.Bool:
-			boolVal, _ := strconv.ParseBool(secretVal)
-			field.Value.SetBool(boolVal)
-		default:
-			allErrs = append(allErrs, fmt.Errorf("cannot set %s: unimplemented kind %s", field.Path, field.Kind))
+		if err := field.Set(secretVal); err != nil {
+			allErrs = append(allErrs, err)
 		}
 	}
 
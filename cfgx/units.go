@@ -0,0 +1,318 @@
+package cfgx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteSize is a number of bytes. It parses a number with an optional
+// unit, e.g. "512", "64KB", "1.5GiB" or "10 MiB". Units are not case
+// sensitive, are decimal (KB, MB, ...) or binary (KiB, MiB, ...), and
+// the B can be left out, e.g. "64k" or "512Mi".
+type ByteSize int64
+
+// Decimal and binary byte sizes.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+	PiB ByteSize = 1024 * TiB
+)
+
+// byteUnits are in the order String tries them, binary first.
+var byteUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB},
+	{"PB", PB}, {"TB", TB}, {"GB", GB}, {"MB", MB}, {"KB", KB},
+}
+
+// ParseByteSize parses a size such as "10MiB". See [ByteSize].
+func ParseByteSize(s string) (ByteSize, error) {
+	num, unit := splitNumber(s)
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || num == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size, ok := byteUnit(unit)
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size %q: unknown unit %q", s, unit)
+	}
+
+	bytes := n * float64(size)
+	switch {
+	case bytes < 0:
+		return 0, fmt.Errorf("invalid byte size %q: must not be negative", s)
+	case bytes >= math.MaxInt64:
+		return 0, fmt.Errorf("invalid byte size %q: too large", s)
+	case bytes != math.Trunc(bytes):
+		return 0, fmt.Errorf("invalid byte size %q: not a whole number of bytes", s)
+	}
+	return ByteSize(bytes), nil
+}
+
+// byteUnit returns the size of the unit, which can leave out the B.
+func byteUnit(unit string) (ByteSize, bool) {
+	unit = strings.ToLower(unit)
+	if unit == "" || unit == "b" {
+		return Byte, true
+	}
+	for _, u := range byteUnits {
+		name := strings.ToLower(u.name)
+		if unit == name || unit == strings.TrimSuffix(name, "b") {
+			return u.size, true
+		}
+	}
+	return 0, false
+}
+
+// String returns the size in the largest unit it is a whole number
+// of, preferring binary units, e.g. "10MiB", "5MB" or "1023B".
+func (b ByteSize) String() string {
+	n, name := b, "B"
+	for _, u := range byteUnits {
+		if b != 0 && b%u.size == 0 && b/u.size < n {
+			n, name = b/u.size, u.name
+		}
+	}
+	return strconv.FormatInt(int64(n), 10) + name
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+func (b ByteSize) quantity() float64 { return float64(b) }
+
+// Percent is a fraction, e.g. 0.75 for 75%. It parses a percentage
+// such as "75%" or "12.5%", or a fraction from -1 to 1 such as "0.75".
+// A number without "%" outside that range, such as "75", is an error
+// rather than 7500%, since it was most likely meant as a percentage.
+type Percent float64
+
+// ParsePercent parses a percentage such as "75%". See [Percent].
+func ParsePercent(s string) (Percent, error) {
+	num, isPercent := strings.CutSuffix(strings.TrimSpace(s), "%")
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	if isPercent {
+		n /= 100
+	} else if math.Abs(n) > 1 {
+		return 0, fmt.Errorf("invalid percentage %q: a fraction must be between -1 and 1, or add %% for a percentage", s)
+	}
+	return Percent(n), nil
+}
+
+// String returns the percentage, e.g. "75%".
+func (p Percent) String() string {
+	// Round away float errors, e.g. 0.07 * 100 = 7.000000000000001
+	n := math.Round(float64(p)*100*1e9) / 1e9
+	return strconv.FormatFloat(n, 'f', -1, 64) + "%"
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (p *Percent) UnmarshalText(text []byte) error {
+	percent, err := ParsePercent(string(text))
+	if err != nil {
+		return err
+	}
+	*p = percent
+	return nil
+}
+
+func (p Percent) quantity() float64 { return float64(p) }
+
+// Rate is a number of events per period. It parses a number, a slash
+// and a unit or duration, e.g. "100/s", "5/min", "1000/h" or "3/10m".
+// A number alone is per second.
+type Rate struct {
+	N   float64
+	Per time.Duration
+}
+
+// rateUnits are the names of the periods, the first one used by String.
+var rateUnits = []struct {
+	names []string
+	per   time.Duration
+}{
+	{[]string{"ns"}, time.Nanosecond},
+	{[]string{"us", "µs"}, time.Microsecond},
+	{[]string{"ms"}, time.Millisecond},
+	{[]string{"s", "sec", "second"}, time.Second},
+	{[]string{"m", "min", "minute"}, time.Minute},
+	{[]string{"h", "hr", "hour"}, time.Hour},
+	{[]string{"d", "day"}, 24 * time.Hour},
+}
+
+// ParseRate parses a rate such as "100/s". See [Rate].
+func ParseRate(s string) (Rate, error) {
+	num, period, hasPeriod := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid rate %q", s)
+	}
+
+	r := Rate{N: n, Per: time.Second}
+	if !hasPeriod {
+		return r, nil
+	}
+
+	period = strings.TrimSpace(period)
+	found := false
+	for _, u := range rateUnits {
+		for _, name := range u.names {
+			// Words can be plural, e.g. "mins", but not "ms"
+			if strings.EqualFold(period, name) || len(name) > 2 && strings.EqualFold(period, name+"s") {
+				r.Per, found = u.per, true
+			}
+		}
+	}
+	if !found {
+		if r.Per, err = time.ParseDuration(period); err != nil {
+			return Rate{}, fmt.Errorf("invalid rate %q: unknown period %q", s, period)
+		}
+	}
+	if r.Per <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: period must be positive", s)
+	}
+	return r, nil
+}
+
+// PerSecond returns the number of events per second.
+func (r Rate) PerSecond() float64 {
+	if r.Per <= 0 {
+		return 0
+	}
+	return r.N / r.Per.Seconds()
+}
+
+// Every returns the time between events, or 0 if there are none.
+func (r Rate) Every() time.Duration {
+	if r.N <= 0 {
+		return 0
+	}
+	return time.Duration(float64(r.Per) / r.N)
+}
+
+// String returns the rate with the unit of the period,
+// or the duration if it has none, e.g. "100/s" or "3/10m0s".
+func (r Rate) String() string {
+	per := r.Per
+	if per == 0 {
+		per = time.Second
+	}
+
+	period := per.String()
+	for _, u := range rateUnits {
+		if u.per == per {
+			period = u.names[0]
+		}
+	}
+	return strconv.FormatFloat(r.N, 'f', -1, 64) + "/" + period
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (r *Rate) UnmarshalText(text []byte) error {
+	rate, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+func (r Rate) quantity() float64 { return r.PerSecond() }
+
+// quantity is implemented by the unit types, to compare them
+// with the min and max tags.
+type quantity interface {
+	quantity() float64
+}
+
+var quantityType = reflect.TypeFor[quantity]()
+
+// unitTypes are the unit types and their placeholders in the usage,
+// with the formats they parse.
+var unitTypes = []struct {
+	t           reflect.Type
+	name, about string
+}{
+	{reflect.TypeFor[ByteSize](), "size", "bytes with an optional unit, e.g. 512, 64KB or 10MiB"},
+	{reflect.TypeFor[Percent](), "percent", "a percentage or a fraction from -1 to 1, e.g. 75% or 0.75"},
+	{reflect.TypeFor[Rate](), "rate", "a number per period, e.g. 100/s, 5/min or 3/10m"},
+}
+
+// unitName returns the placeholder of a unit type, if it is one.
+func unitName(t reflect.Type) (string, bool) {
+	for _, u := range unitTypes {
+		if u.t == t {
+			return u.name, true
+		}
+	}
+	return "", false
+}
+
+// compareQuantity compares the value to the tag argument,
+// parsed like the value, e.g. "1MiB".
+func compareQuantity(v quantity, t reflect.Type, arg string) (int, error) {
+	parsed, err := parseValue(t, arg, nil)
+	if err != nil {
+		return 0, err
+	}
+	q, ok := parsed.Interface().(quantity)
+	if !ok {
+		return 0, errors.New("not a quantity")
+	}
+	return compare(v.quantity(), q.quantity()), nil
+}
+
+// splitNumber splits a number from the unit after it, trimming spaces.
+func splitNumber(s string) (num, unit string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '+' && r != '-'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
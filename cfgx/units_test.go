@@ -0,0 +1,214 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type unitsConfig struct {
+	MaxBody    cfgx.ByteSize `default:"10MiB" max:"1GiB"`
+	CacheRatio cfgx.Percent  `default:"75%"`
+	Rate       cfgx.Rate     `default:"100/s" min:"1/min"`
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want cfgx.ByteSize
+		str  string
+	}{
+		{"512", 512, "512B"},
+		{"0", 0, "0B"},
+		{"64KB", 64 * cfgx.KB, "64KB"},
+		{"64k", 64 * cfgx.KB, "64KB"},
+		{"10MiB", 10 * cfgx.MiB, "10MiB"},
+		{"10 mib", 10 * cfgx.MiB, "10MiB"},
+		{"512Mi", 512 * cfgx.MiB, "512MiB"},
+		{"1.5GiB", 1536 * cfgx.MiB, "1536MiB"},
+		{"2TB", 2 * cfgx.TB, "2TB"},
+		{"1024KiB", cfgx.MiB, "1MiB"},
+	}
+	for _, tt := range tests {
+		got, err := cfgx.ParseByteSize(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || got.String() != tt.str {
+			t.Errorf("%s: wanted %d (%s), got %d (%s)", tt.in, tt.want, tt.str, got, got)
+		}
+	}
+
+	for _, in := range []string{"", "MiB", "10XB", "-1KB", "0.5B", "1e3", "99999PiB"} {
+		if _, err := cfgx.ParseByteSize(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want cfgx.Percent
+		str  string
+	}{
+		{"75%", 0.75, "75%"},
+		{"12.5 %", 0.125, "12.5%"},
+		{"0.07", 0.07, "7%"},
+		{"1", 1, "100%"},
+		{"150%", 1.5, "150%"},
+		{"-0.25", -0.25, "-25%"},
+	}
+	for _, tt := range tests {
+		got, err := cfgx.ParsePercent(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || got.String() != tt.str {
+			t.Errorf("%s: wanted %v (%s), got %v (%s)", tt.in, float64(tt.want), tt.str, float64(got), got)
+		}
+	}
+
+	for _, in := range []string{"", "%", "half", "75", "1.5", "-2"} {
+		if _, err := cfgx.ParsePercent(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want cfgx.Rate
+		str  string
+	}{
+		{"100/s", cfgx.Rate{N: 100, Per: time.Second}, "100/s"},
+		{"100", cfgx.Rate{N: 100, Per: time.Second}, "100/s"},
+		{"5/min", cfgx.Rate{N: 5, Per: time.Minute}, "5/m"},
+		{"5 / mins", cfgx.Rate{N: 5, Per: time.Minute}, "5/m"},
+		{"10/ms", cfgx.Rate{N: 10, Per: time.Millisecond}, "10/ms"},
+		{"1000/hour", cfgx.Rate{N: 1000, Per: time.Hour}, "1000/h"},
+		{"2.5/day", cfgx.Rate{N: 2.5, Per: 24 * time.Hour}, "2.5/d"},
+		{"3/10m", cfgx.Rate{N: 3, Per: 10 * time.Minute}, "3/10m0s"},
+	}
+	for _, tt := range tests {
+		got, err := cfgx.ParseRate(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || got.String() != tt.str {
+			t.Errorf("%s: wanted %+v (%s), got %+v (%s)", tt.in, tt.want, tt.str, got, got)
+		}
+	}
+
+	r := cfgx.Rate{N: 10, Per: time.Minute}
+	if r.PerSecond() != 10.0/60 || r.Every() != 6*time.Second {
+		t.Errorf("unexpected PerSecond %v or Every %v", r.PerSecond(), r.Every())
+	}
+
+	for _, in := range []string{"", "/s", "100/fortnight", "100/-1s", "100/0s"} {
+		if _, err := cfgx.ParseRate(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestUnits(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Sources", func(t *testing.T) {
+		t.Parallel()
+
+		file := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(file, []byte("max_body: 64KB\ncache_ratio: 0.5\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var cfg unitsConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Env:     map[string]string{"RATE": "5/min"},
+			Args:    []string{},
+			Sources: []cfgx.Source{cfgx.NewFileSource(file)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := unitsConfig{MaxBody: 64 * cfgx.KB, CacheRatio: 0.5, Rate: cfgx.Rate{N: 5, Per: time.Minute}}
+		if cfg != want {
+			t.Errorf("wanted %+v, got %+v", want, cfg)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg unitsConfig
+		err := cfgx.Parse(&cfg, cfgx.Options{
+			Env:  map[string]string{},
+			Args: []string{"--max-body", "2GiB", "--rate", "30/h"},
+		})
+		if err == nil {
+			t.Fatal("expected validation errors")
+		}
+		for _, want := range []string{"'MaxBody': must be at most 1GiB", "'Rate': must be at least 1/min"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in %v", want, err)
+			}
+		}
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		if err := cfgx.WriteUsage(&buf, &unitsConfig{}, cfgx.Options{ProgramName: "app", SkipEnv: true}); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"--max-body size", "--cache-ratio percent", "--rate rate", "Formats:\n  size "} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected %q in the usage:\n%s", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		if err := cfgx.WriteJSONSchema(&buf, &unitsConfig{}, cfgx.Options{}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"default": "10MiB"`) || strings.Contains(buf.String(), "maximum") {
+			t.Errorf("unexpected schema:\n%s", buf.String())
+		}
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := unitsConfig{MaxBody: 10 * cfgx.MiB, CacheRatio: 0.75, Rate: cfgx.Rate{N: 100, Per: time.Second}}
+		var buf bytes.Buffer
+		if err := cfgx.ExportEnv(&buf, &cfg, cfgx.ExportOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		want := "MAX_BODY=10MiB\nCACHE_RATIO=75%\nRATE=100/s\n"
+		if buf.String() != want {
+			t.Errorf("wanted %q, got %q", want, buf.String())
+		}
+	})
+}
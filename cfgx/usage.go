@@ -0,0 +1,235 @@
+package cfgx
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// usageRow is one field in the usage table.
+type usageRow struct {
+	flag, short, typ string
+	args             bool // Collects the positional args, shown as "name..."
+	env, file, def   string
+	required         bool
+	desc             string
+}
+
+// WriteUsage writes the usage page shown for -h and --help: a table of
+// each field's flag, environment variable, default, whether it is
+// required and its description. If a [DockerSecretsSource] or
+// [FileContentSource] is in opts.Sources the secret file name is included.
+func WriteUsage(w io.Writer, cfg any, opts Options) error {
+	opts = setOptions(opts)
+
+	rows, err := usageRows(cfg, opts)
+	if err != nil {
+		return err
+	}
+	hasFile := slices.ContainsFunc(rows, func(r usageRow) bool { return r.file != "" })
+
+	fmt.Fprintf(w, "Usage of %s:\n\n", opts.ProgramName)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"FLAG", "ENV"}
+	if hasFile {
+		header = append(header, "FILE")
+	}
+	header = append(header, "DEFAULT", "REQUIRED", "DESCRIPTION")
+	fmt.Fprintf(tw, "  %s\n", strings.Join(header, "\t"))
+
+	for _, r := range rows {
+		flagCol := "--" + r.flag
+		if r.short != "" {
+			flagCol = "-" + r.short + ", " + flagCol
+		}
+		if r.args {
+			flagCol = r.flag + "..."
+		}
+		if r.typ != "" {
+			flagCol += " " + r.typ
+		}
+
+		cols := []string{flagCol, r.env}
+		if hasFile {
+			cols = append(cols, r.file)
+		}
+		cols = append(cols, r.def, yesNo(r.required), r.desc)
+		fmt.Fprintf(tw, "  %s\n", strings.Join(cols, "\t"))
+	}
+
+	// Explain the formats of the unit types
+	if formats := unitFormats(rows); len(formats) > 0 {
+		fmt.Fprintf(tw, "\nFormats:\n")
+		for _, f := range formats {
+			fmt.Fprintf(tw, "  %s\t%s\n", f.name, f.about)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// WriteUsageMarkdown writes the same table as [WriteUsage] as a
+// Markdown table, e.g. for a README.
+func WriteUsageMarkdown(w io.Writer, cfg any, opts Options) error {
+	opts = setOptions(opts)
+
+	rows, err := usageRows(cfg, opts)
+	if err != nil {
+		return err
+	}
+	hasFile := slices.ContainsFunc(rows, func(r usageRow) bool { return r.file != "" })
+
+	header := []string{"Flag", "Env"}
+	if hasFile {
+		header = append(header, "File")
+	}
+	header = append(header, "Default", "Required", "Description")
+
+	fmt.Fprintf(w, "| %s |\n", strings.Join(header, " | "))
+	fmt.Fprintf(w, "|%s\n", strings.Repeat("---|", len(header)))
+
+	for _, r := range rows {
+		flagCol := code("--" + r.flag)
+		if r.short != "" {
+			flagCol = code("-"+r.short) + ", " + flagCol
+		}
+		if r.args {
+			flagCol = code(r.flag + "...")
+		}
+
+		cols := []string{flagCol, code(r.env)}
+		if hasFile {
+			cols = append(cols, code(r.file))
+		}
+		cols = append(cols, code(r.def), yesNo(r.required), strings.ReplaceAll(r.desc, "|", `\|`))
+
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cols, " | ")); err != nil {
+			return err
+		}
+	}
+
+	if formats := unitFormats(rows); len(formats) > 0 {
+		fmt.Fprintf(w, "\nFormats:\n\n")
+		for _, f := range formats {
+			if _, err := fmt.Fprintf(w, "- %s: %s\n", code(f.name), f.about); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// usageRows walks a zero value of the config type so every
+// field is listed, in declaration order.
+func usageRows(cfg any, opts Options) ([]usageRow, error) {
+	t := reflect.TypeOf(cfg)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, ErrNotPointerToStruct
+	}
+
+	fields := walkStruct(reflect.New(t.Elem()).Elem(), "", nil, opts.Decoders)
+
+	var fileSource *FileContentSource
+	for _, s := range opts.Sources {
+		switch s := s.(type) {
+		case *DockerSecretsSource:
+			fileSource = &s.FileContentSource
+		case *FileContentSource:
+			fileSource = s
+		}
+	}
+
+	var rows []usageRow
+	for _, field := range orderedFields(fields) {
+		name, short := flagNames(field)
+
+		row := usageRow{
+			flag:     name,
+			short:    short,
+			typ:      typeName(field),
+			required: !isOptional(field) && !hasDefault(field),
+			desc:     field.Tag.Get(tagDescription),
+		}
+
+		if field.Tag.Get(tagArgs) == "true" {
+			row = usageRow{flag: name, args: true, required: row.required, desc: row.desc}
+		}
+
+		if !opts.SkipEnv {
+			row.env = envName(field, opts.EnvPrefix)
+		}
+		if fileSource != nil {
+			row.file = fileSource.fileName(field)
+		}
+		if def, ok := field.Tag.Lookup(tagDefault); ok {
+			row.def = def
+			if field.isSecret() && def != "" {
+				row.def = Redacted
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if hasBuildInfo(t.Elem()) && !usesFlagName(fields, versionFlag) {
+		version := newVersionFlag().field
+		rows = append(rows, usageRow{flag: version.Path, desc: version.Description})
+	}
+
+	return rows, nil
+}
+
+// unitFormat is the placeholder of a unit type and the format it parses.
+type unitFormat struct {
+	name, about string
+}
+
+// unitFormats returns the formats of the unit types shown in the rows.
+func unitFormats(rows []usageRow) []unitFormat {
+	var formats []unitFormat
+	for _, u := range unitTypes {
+		if slices.ContainsFunc(rows, func(r usageRow) bool { return r.typ == u.name }) {
+			formats = append(formats, unitFormat{u.name, u.about})
+		}
+	}
+	return formats
+}
+
+// typeName is the placeholder shown after the flag. Bools have none.
+func typeName(field ConfigField) string {
+	t := field.Value.Type()
+
+	if name, ok := unitName(t); ok {
+		return name
+	}
+
+	switch {
+	case field.Kind == reflect.Bool:
+		return ""
+	case t == durationType:
+		return "duration"
+	default:
+		return t.String()
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// code formats a Markdown code span, or nothing if empty.
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,128 @@
+package cfgx_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+type usageConfig struct {
+	Port     int           `default:"8080" short:"p" desc:"The server port"`
+	Debug    bool          `optional:"true" short:"d" desc:"Enable debug logging"`
+	Timeout  time.Duration `default:"5s"`
+	Password string        `default:"changeme" secret:"true" dsec:"db_pass"`
+	Log      struct {
+		Level string `default:"info" env:"LOG_LEVEL" desc:"Minimum log level"`
+	}
+	Name string `desc:"The service name"`
+}
+
+func TestHelp(t *testing.T) {
+	t.Parallel()
+
+	for _, arg := range []string{"-h", "--help"} {
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+			var cfg usageConfig
+			var buf bytes.Buffer
+
+			err := cfgx.Parse(&cfg, cfgx.Options{
+				ProgramName: "app",
+				Args:        []string{arg},
+				SkipEnv:     true,
+				Output:      &buf,
+			})
+			if !errors.Is(err, flag.ErrHelp) {
+				t.Fatalf("wanted flag.ErrHelp, got %v", err)
+			}
+
+			if !strings.Contains(buf.String(), "Usage of app:") {
+				t.Errorf("usage not written:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteUsage(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := cfgx.WriteUsage(&buf, &usageConfig{}, cfgx.Options{
+		ProgramName: "app",
+		EnvPrefix:   "APP",
+		Sources:     []cfgx.Source{cfgx.NewDockerSecretsSource()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	lines := strings.Split(out, "\n")
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"FLAG", []string{"ENV", "FILE", "DEFAULT", "REQUIRED", "DESCRIPTION"}},
+		{"--port", []string{"-p, --port int", "APP_PORT", "port", "8080", "no", "The server port"}},
+		{"--debug", []string{"-d, --debug ", "APP_DEBUG", "no", "Enable debug logging"}},
+		{"--timeout", []string{"--timeout duration", "APP_TIMEOUT", "5s"}},
+		{"--password", []string{"db_pass", "[REDACTED]"}},
+		{"--log-level", []string{"LOG_LEVEL", "info", "Minimum log level"}},
+		{"--name", []string{"APP_NAME", "yes", "The service name"}},
+	}
+
+	for _, tt := range tests {
+		var found string
+		for _, line := range lines {
+			if strings.Contains(line, tt.line) {
+				found = line
+				break
+			}
+		}
+		if found == "" {
+			t.Errorf("missing line for %s:\n%s", tt.line, out)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(found, want) {
+				t.Errorf("%s: line missing %q: %q", tt.line, want, found)
+			}
+		}
+	}
+
+	if strings.Contains(out, "changeme") {
+		t.Errorf("usage leaks secret default:\n%s", out)
+	}
+
+	// Declaration order
+	if strings.Index(out, "--port") > strings.Index(out, "--log-level") {
+		t.Errorf("wanted fields in declaration order:\n%s", out)
+	}
+}
+
+func TestWriteUsageMarkdown(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := cfgx.WriteUsageMarkdown(&buf, &usageConfig{}, cfgx.Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"| Flag | Env | Default | Required | Description |",
+		"|---|---|---|---|---|",
+		"| `-p`, `--port` | `PORT` | `8080` | no | The server port |",
+		"| `--name` | `NAME` |  | yes | The service name |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package cfgx
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	tagMin     = "min"     // Minimum for numbers and durations
+	tagMax     = "max"     // Maximum for numbers and durations
+	tagMinLen  = "minlen"  // Minimum length of strings, slices and maps
+	tagMaxLen  = "maxlen"  // Maximum length of strings, slices and maps
+	tagOneOf   = "oneof"   // Comma separated list of allowed values
+	tagPattern = "pattern" // Regular expression the value must match
+	tagFormat  = "format"  // One of url, hostport or email
+)
+
+// Validator is implemented by config structs, or nested structs, with
+// rules across fields. Parse calls Validate after the validation tags
+// and reports the errors with the others.
+type Validator interface {
+	Validate() error
+}
+
+// validator checks a field against the argument of its tag.
+// It returns the reason it failed or an empty string.
+type validator struct {
+	tag   string
+	check func(field ConfigField, arg string) string
+}
+
+// Validators in the order they are reported.
+var validators = []validator{
+	{tagMin, checkMin},
+	{tagMax, checkMax},
+	{tagMinLen, checkMinLen},
+	{tagMaxLen, checkMaxLen},
+	{tagOneOf, checkOneOf},
+	{tagPattern, checkPattern},
+	{tagFormat, checkFormat},
+}
+
+// validateStructs calls Validate on the nested structs and then on the
+// struct itself. Errors from nested structs that are not a
+// [ValidationError] are prefixed with the path.
+func validateStructs(v reflect.Value, path string, decoders map[reflect.Type]DecodeFunc) []error {
+	var allErrs []error
+
+	t := v.Type()
+	for i := range t.NumField() {
+		structField := t.Field(i)
+		fieldVal := v.Field(i)
+		if !structField.IsExported() || fieldVal.Kind() != reflect.Struct || hasDecoder(fieldVal.Type(), decoders) {
+			continue
+		}
+
+		nested := structField.Name
+		if path != "" {
+			nested = path + "." + nested
+		}
+		allErrs = append(allErrs, validateStructs(fieldVal, nested, decoders)...)
+	}
+
+	// Use the pointer so Validate can have either receiver
+	validator, ok := v.Interface().(Validator)
+	if v.CanAddr() {
+		validator, ok = v.Addr().Interface().(Validator)
+	}
+	if !ok {
+		return allErrs
+	}
+
+	for _, err := range flattenErrors(validator.Validate()) {
+		var valErr *ValidationError
+		if path != "" && !errors.As(err, &valErr) {
+			err = fmt.Errorf("%s: %w", path, err)
+		}
+		allErrs = append(allErrs, err)
+	}
+
+	return allErrs
+}
+
+// flattenErrors unwraps a [MultiError] or [errors.Join] into its errors.
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if multi, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range multi.Unwrap() {
+			errs = append(errs, flattenErrors(err)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+// Error if required fields are missing
+func validateRequired(fields map[string]ConfigField) []error {
+	var allErrs []error
+
+	for _, path := range slices.Sorted(maps.Keys(fields)) {
+		field := fields[path]
+
+		// Skip if optional, or if its references failed and were reported
+		if isOptional(field) || field.state.failed {
+			continue
+		}
+
+		// If it is required and no source provided it add error,
+		// so an explicit zero value such as --retries=0 is allowed,
+		// but not an empty string such as API_KEY=
+		switch {
+		case !field.state.provided:
+			allErrs = append(allErrs, &ValidationError{Field: path, Reason: "is required"})
+		case field.Kind == reflect.String && field.Value.Len() == 0:
+			allErrs = append(allErrs, &ValidationError{Field: path, Reason: "is required and must not be empty"})
+		}
+	}
+
+	return allErrs
+}
+
+// validateTags checks the validation tags after all sources have run.
+// Fields that no source provided are skipped, as are fields whose
+// references failed, since those are already reported.
+func validateTags(fields map[string]ConfigField) []error {
+	var allErrs []error
+
+	for _, path := range slices.Sorted(maps.Keys(fields)) {
+		field := fields[path]
+
+		if !field.state.provided || field.state.failed {
+			continue
+		}
+
+		for _, v := range validators {
+			arg, ok := field.Tag.Lookup(v.tag)
+			if !ok {
+				continue
+			}
+
+			if reason := v.check(field, arg); reason != "" {
+				allErrs = append(allErrs, &ValidationError{
+					Field:  path,
+					Value:  field.display(),
+					Reason: reason,
+				})
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func isOptional(field ConfigField) bool {
+	optVal, exists := field.Tag.Lookup(tagOptional)
+	return exists && optVal != "false"
+}
+
+func checkMin(field ConfigField, arg string) string {
+	cmp, err := compareNumber(field.Value, arg)
+	if err != nil {
+		return fmt.Sprintf("invalid min tag: %v", err)
+	}
+	if cmp < 0 {
+		return "must be at least " + arg
+	}
+	return ""
+}
+
+func checkMax(field ConfigField, arg string) string {
+	cmp, err := compareNumber(field.Value, arg)
+	if err != nil {
+		return fmt.Sprintf("invalid max tag: %v", err)
+	}
+	if cmp > 0 {
+		return "must be at most " + arg
+	}
+	return ""
+}
+
+// compareNumber compares the value to the tag argument, parsed as
+// the same kind. Durations use time.ParseDuration, and units such as
+// [ByteSize] are parsed like the value.
+func compareNumber(v reflect.Value, arg string) (int, error) {
+	if q, ok := v.Interface().(quantity); ok {
+		return compareQuantity(q, v.Type(), arg)
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Int(), int64(d)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Int(), n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Uint(), n), nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Float(), n), nil
+	default:
+		return 0, fmt.Errorf("not supported for kind %s", v.Kind())
+	}
+}
+
+func compare[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func checkMinLen(field ConfigField, arg string) string {
+	n, err := length(field.Value, arg)
+	if err != nil {
+		return fmt.Sprintf("invalid minlen tag: %v", err)
+	}
+	if want, _ := strconv.Atoi(arg); n < want {
+		return "length must be at least " + arg
+	}
+	return ""
+}
+
+func checkMaxLen(field ConfigField, arg string) string {
+	n, err := length(field.Value, arg)
+	if err != nil {
+		return fmt.Sprintf("invalid maxlen tag: %v", err)
+	}
+	if want, _ := strconv.Atoi(arg); n > want {
+		return "length must be at most " + arg
+	}
+	return ""
+}
+
+// length returns the number of characters or elements. It also
+// checks that the tag argument is a number.
+func length(v reflect.Value, arg string) (int, error) {
+	if _, err := strconv.Atoi(arg); err != nil {
+		return 0, err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len(), nil
+	default:
+		return 0, fmt.Errorf("not supported for kind %s", v.Kind())
+	}
+}
+
+func checkOneOf(field ConfigField, arg string) string {
+	allowed := splitList(arg, defaultSeparator)
+
+	for _, s := range fieldStrings(field) {
+		if !slices.Contains(allowed, s) {
+			return fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+	return ""
+}
+
+func checkPattern(field ConfigField, arg string) string {
+	re, err := regexp.Compile(arg)
+	if err != nil {
+		return fmt.Sprintf("invalid pattern tag: %v", err)
+	}
+
+	for _, s := range fieldStrings(field) {
+		if !re.MatchString(s) {
+			return fmt.Sprintf("must match pattern %s", arg)
+		}
+	}
+	return ""
+}
+
+func checkFormat(field ConfigField, arg string) string {
+	var check func(string) bool
+
+	switch arg {
+	case "url":
+		check = func(s string) bool {
+			u, err := url.Parse(s)
+			return err == nil && u.Scheme != "" && u.Host != ""
+		}
+	case "hostport":
+		check = func(s string) bool {
+			_, port, err := net.SplitHostPort(s)
+			if err != nil {
+				return false
+			}
+			_, err = strconv.ParseUint(port, 10, 16)
+			return err == nil
+		}
+	case "email":
+		check = func(s string) bool {
+			addr, err := mail.ParseAddress(s)
+			return err == nil && addr.Address == s
+		}
+	default:
+		return fmt.Sprintf("invalid format tag: unknown format %q", arg)
+	}
+
+	for _, s := range fieldStrings(field) {
+		if !check(s) {
+			return "must be a valid " + arg
+		}
+	}
+	return ""
+}
+
+// fieldStrings returns the string form of the value, or of each
+// element for slices.
+func fieldStrings(field ConfigField) []string {
+	if field.isList() && field.Kind == reflect.Slice {
+		strs := make([]string, field.Value.Len())
+		for i := range field.Value.Len() {
+			strs[i] = stringOf(field.Value.Index(i))
+		}
+		return strs
+	}
+	return []string{stringOf(field.Value)}
+}
+
+// stringOf formats the value, using a String method on the pointer if it has one.
+func stringOf(v reflect.Value) string {
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,202 @@
+package cfgx_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erlorenz/go-toolbox/cfgx"
+)
+
+func TestValidationTags(t *testing.T) {
+	t.Parallel()
+
+	type validated struct {
+		Port     int           `default:"8080" min:"1" max:"65535"`
+		Timeout  time.Duration `default:"5s" min:"1s" max:"1m"`
+		Ratio    float64       `default:"0.5" min:"0" max:"1"`
+		Name     string        `default:"api" minlen:"2" maxlen:"8"`
+		Level    string        `default:"info" oneof:"debug,info,warn,error"`
+		Region   string        `default:"us-east-1" pattern:"^[a-z]+-[a-z]+-[0-9]$"`
+		BaseURL  string        `default:"https://example.com" format:"url"`
+		Addr     string        `default:"localhost:5432" format:"hostport"`
+		Admin    string        `default:"admin@example.com" format:"email"`
+		Hosts    []string      `default:"a,b" minlen:"1" oneof:"a,b,c"`
+		Optional int           `optional:"true" min:"10"`
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+		var cfg validated
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		var cfg validated
+
+		args := []string{
+			"--port=0",
+			"--timeout=2m",
+			"--ratio=1.5",
+			"--name=a",
+			"--level=trace",
+			"--region=mars",
+			"--base-url=example.com",
+			"--addr=localhost",
+			"--admin=nobody",
+			"--hosts=a,d",
+			"--optional=5",
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{Args: args, SkipEnv: true})
+
+		var multiErr *cfgx.MultiError
+		if !errors.As(err, &multiErr) {
+			t.Fatalf("wanted MultiError, got %v", err)
+		}
+
+		got := map[string]bool{}
+		for _, err := range multiErr.Errors {
+			var valErr *cfgx.ValidationError
+			if !errors.As(err, &valErr) {
+				t.Fatalf("wanted ValidationError, got %v", err)
+			}
+			got[valErr.Field] = true
+		}
+
+		for _, field := range []string{"Timeout", "Ratio", "Name", "Level", "Region", "BaseURL", "Addr", "Admin", "Hosts", "Optional"} {
+			if !got[field] {
+				t.Errorf("%s: wanted validation error", field)
+			}
+		}
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Port int `default:"70000" max:"65535"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+
+		var valErr *cfgx.ValidationError
+		if !errors.As(err, &valErr) {
+			t.Fatalf("wanted ValidationError, got %v", err)
+		}
+		if valErr.Field != "Port" || valErr.Value != 70000 {
+			t.Errorf("wanted Port=70000, got %s=%v", valErr.Field, valErr.Value)
+		}
+	})
+
+	t.Run("MissingRequired", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Port  int    `min:"1"`
+			Level string `oneof:"debug,info"`
+			Name  string `default:"${VALIDATE_UNDEFINED}" minlen:"3" resolve:"true"`
+		}
+
+		// Only the missing and unresolved fields are reported, not their tags
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, Env: map[string]string{}})
+
+		var multiErr *cfgx.MultiError
+		if !errors.As(err, &multiErr) || len(multiErr.Errors) != 3 {
+			t.Fatalf("wanted 3 errors, got %v", err)
+		}
+		for _, err := range multiErr.Errors {
+			var valErr *cfgx.ValidationError
+			if errors.As(err, &valErr) && valErr.Reason != "is required" {
+				t.Errorf("wanted only required errors, got %v", err)
+			}
+		}
+	})
+
+	t.Run("InvalidTag", func(t *testing.T) {
+		t.Parallel()
+		var cfg struct {
+			Name string `default:"api" min:"1"`
+		}
+
+		err := cfgx.Parse(&cfg, cfgx.Options{SkipFlags: true, SkipEnv: true})
+		if err == nil {
+			t.Fatal("wanted error for min on a string")
+		}
+	})
+}
+
+type tlsConfig struct {
+	Cert string `optional:"true"`
+	Key  string `optional:"true"`
+}
+
+func (c tlsConfig) Validate() error {
+	if c.Cert != "" && c.Key == "" {
+		return errors.New("cert requires key")
+	}
+	return nil
+}
+
+type serverConfig struct {
+	Port    int `min:"1"`
+	TLS     tlsConfig
+	Admin   string `optional:"true"`
+	Readers int    `optional:"true"`
+}
+
+func (c *serverConfig) Validate() error {
+	var errs []error
+	if c.Admin != "" && c.Readers == 0 {
+		errs = append(errs, &cfgx.ValidationError{Field: "Readers", Value: c.Readers, Reason: "is required with Admin"})
+	}
+	if c.Port == 443 && c.TLS.Cert == "" {
+		errs = append(errs, errors.New("port 443 requires TLS"))
+	}
+	return errors.Join(errs...)
+}
+
+func TestValidateHook(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := cfgx.Load[serverConfig](cfgx.WithArgs("--port", "8443", "--tls-cert", "c", "--tls-key", "k"), cfgx.WithSkipEnv(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := cfgx.Load[serverConfig](
+			cfgx.WithArgs("--port", "0", "--tls-cert", "c", "--admin", "root"),
+			cfgx.WithSkipEnv(true),
+		)
+
+		var multi *cfgx.MultiError
+		if !errors.As(err, &multi) {
+			t.Fatalf("expected MultiError, got %v", err)
+		}
+
+		// Tag validation, then nested structs, then the root
+		want := []string{
+			"validation error for field 'Port': must be at least 1",
+			"TLS: cert requires key",
+			"validation error for field 'Readers': is required with Admin",
+		}
+		if len(multi.Errors) != len(want) {
+			t.Fatalf("expected %d errors, got %v", len(want), multi.Errors)
+		}
+		for i, w := range want {
+			if multi.Errors[i].Error() != w {
+				t.Errorf("error %d: wanted %q, got %q", i, w, multi.Errors[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,291 @@
+package cfgx
+
+import (
+	"cmp"
+	"encoding"
+	"flag"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSeparator = ","
+
+// DecodeFunc parses a raw string value into a value of the registered type.
+type DecodeFunc func(raw string) (any, error)
+
+// builtinDecoders are used for types that do not implement
+// [encoding.TextUnmarshaler] but are common in configs.
+var builtinDecoders = map[reflect.Type]DecodeFunc{
+	reflect.TypeFor[url.URL](): func(raw string) (any, error) {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	},
+	reflect.TypeFor[*url.URL](): func(raw string) (any, error) {
+		return url.Parse(raw)
+	},
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	flagValueType       = reflect.TypeFor[flag.Value]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// Set parses the raw string into the field. It is the conversion
+// used by all the built-in sources and should be used by custom sources.
+//
+// Values are decoded in this order: a decoder registered in
+// [Options.Decoders], [encoding.TextUnmarshaler], [flag.Value],
+// and then the field's kind. Slices and maps are split on the
+// separator (tag "sep", defaults to ",") and map entries are
+// written as key=value.
+//
+// Values with a reference such as "file:/run/secrets/db" or
+// "${DB.Host}" are kept and resolved after all sources have run.
+func (f ConfigField) Set(raw string) error {
+	if f.deferred([]string{raw}, false) {
+		return nil
+	}
+	return f.set(raw)
+}
+
+func (f ConfigField) set(raw string) error {
+	if f.isList() {
+		return f.setItems(splitList(raw, f.separator()))
+	}
+
+	v, err := parseValue(f.Value.Type(), raw, f.decoders)
+	if err != nil {
+		return f.setError(err)
+	}
+	f.Value.Set(v)
+	f.markSet()
+	return nil
+}
+
+// SetItems sets a slice or map field from already split items,
+// e.g. a list from a config file or repeated flags.
+// Other fields take the last item.
+func (f ConfigField) SetItems(items []string) error {
+	if !f.isList() {
+		if len(items) == 0 {
+			return nil
+		}
+		return f.Set(items[len(items)-1])
+	}
+
+	if f.deferred(items, true) {
+		return nil
+	}
+	return f.setItems(items)
+}
+
+func (f ConfigField) setItems(items []string) error {
+	t := f.Value.Type()
+
+	switch f.Kind {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(t, 0, len(items))
+		for _, item := range items {
+			v, err := parseValue(t.Elem(), item, f.decoders)
+			if err != nil {
+				return f.setError(err)
+			}
+			slice = reflect.Append(slice, v)
+		}
+		f.Value.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(t, len(items))
+		for _, item := range items {
+			key, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return f.setError(fmt.Errorf("map entry %q is not key=value", item))
+			}
+			k, err := parseValue(t.Key(), strings.TrimSpace(key), f.decoders)
+			if err != nil {
+				return f.setError(err)
+			}
+			v, err := parseValue(t.Elem(), strings.TrimSpace(val), f.decoders)
+			if err != nil {
+				return f.setError(err)
+			}
+			m.SetMapIndex(k, v)
+		}
+		f.Value.Set(m)
+	}
+	f.markSet()
+	return nil
+}
+
+// setError annotates an error with the path, hiding the value of secrets.
+func (f ConfigField) setError(err error) error {
+	if f.isSecret() {
+		err = &redactedError{err}
+	}
+	return &fieldError{path: f.Path, err: err}
+}
+
+// markSet records that the current source set the field.
+func (f ConfigField) markSet() {
+	if f.state != nil {
+		f.state.set = true
+	}
+}
+
+// isList reports whether the field is a slice or map that is split
+// into items. Types with a decoder are parsed as a whole, e.g. net.IP is a []byte.
+func (f ConfigField) isList() bool {
+	return isListKind(f.Kind) && !hasDecoder(f.Value.Type(), f.decoders)
+}
+
+func (f ConfigField) separator() string {
+	return cmp.Or(f.Tag.Get(tagSeparator), defaultSeparator)
+}
+
+// hasDecoder reports whether the type has a registered decoder or
+// implements one of the decoding interfaces.
+func hasDecoder(t reflect.Type, decoders map[reflect.Type]DecodeFunc) bool {
+	if _, ok := decoders[t]; ok {
+		return true
+	}
+	if _, ok := builtinDecoders[t]; ok {
+		return true
+	}
+	for _, iface := range []reflect.Type{textUnmarshalerType, flagValueType} {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseValue parses raw into a new value of type t.
+func parseValue(t reflect.Type, raw string, decoders map[reflect.Type]DecodeFunc) (reflect.Value, error) {
+	// Registered decoders
+	decode, ok := decoders[t]
+	if !ok {
+		decode, ok = builtinDecoders[t]
+	}
+	if ok {
+		res, err := decode(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.ValueOf(res)
+		if !v.IsValid() || !v.Type().AssignableTo(t) {
+			return reflect.Value{}, fmt.Errorf("decoder for %s returned %T", t, res)
+		}
+		return v, nil
+	}
+
+	// Interfaces are implemented on the pointer, so decode into a
+	// new value and dereference it unless t is the pointer itself.
+	ptr, elem := reflect.PointerTo(t), t
+	if t.Kind() == reflect.Pointer {
+		ptr, elem = t, t.Elem()
+	}
+
+	if ptr.Implements(textUnmarshalerType) {
+		v := reflect.New(elem)
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return reflect.Value{}, err
+		}
+		return derefTo(v, t), nil
+	}
+
+	if ptr.Implements(flagValueType) {
+		v := reflect.New(elem)
+		if err := v.Interface().(flag.Value).Set(raw); err != nil {
+			return reflect.Value{}, err
+		}
+		return derefTo(v, t), nil
+	}
+
+	return parseKind(t, raw, decoders)
+}
+
+// Parse by the kind of the type.
+func parseKind(t reflect.Type, raw string, decoders map[reflect.Type]DecodeFunc) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+
+	// Handle time.Duration specially (it's an int64 alias)
+	if t == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(int64(d))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intVal, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(intVal)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		uintVal, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(uintVal)
+	case reflect.Float32, reflect.Float64:
+		floatVal, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(floatVal)
+	case reflect.Bool:
+		boolVal, err := strconv.ParseBool(raw)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(boolVal)
+	case reflect.Pointer:
+		elem, err := parseValue(t.Elem(), raw, decoders)
+		if err != nil {
+			return v, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	default:
+		return v, fmt.Errorf("unimplemented kind %s", t.Kind())
+	}
+	return v, nil
+}
+
+func derefTo(ptr reflect.Value, t reflect.Type) reflect.Value {
+	if t.Kind() == reflect.Pointer {
+		return ptr
+	}
+	return ptr.Elem()
+}
+
+// isListKind reports whether the kind is a slice or map.
+func isListKind(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Map
+}
+
+// Split on the separator and trim the items. An empty string is an empty list.
+func splitList(raw, sep string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return []string{}
+	}
+	items := strings.Split(raw, sep)
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return items
+}
@@ -2,7 +2,11 @@ module github.com/erlorenz/go-toolbox
 
 go 1.24
 
-require github.com/jackc/pgx/v5 v5.8.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/jackc/pgx/v5 v5.8.0
+	go.yaml.in/yaml/v3 v3.0.4
+)
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect